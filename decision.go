@@ -0,0 +1,76 @@
+package plex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlaybackDecision is how a client would play media
+type PlaybackDecision string
+
+// playback decisions, from the cheapest to the most expensive for the server
+const (
+	// DecisionDirectPlay plays the file as is
+	DecisionDirectPlay PlaybackDecision = "directplay"
+	// DecisionDirectStream copies the video into another container, transcoding the audio if needed
+	DecisionDirectStream PlaybackDecision = "directstream"
+	// DecisionTranscode transcodes the video
+	DecisionTranscode PlaybackDecision = "transcode"
+)
+
+// ClientProfile describes what a client can play. Empty lists and zero limits allow anything.
+type ClientProfile struct {
+	// Containers i.e. mp4 or mkv
+	Containers []string
+	// VideoCodecs i.e. h264 or hevc
+	VideoCodecs []string
+	// AudioCodecs i.e. aac or ac3
+	AudioCodecs []string
+	// MaxHeight is the highest video resolution, i.e. 1080
+	MaxHeight int
+	// MaxBitrate in kbps
+	MaxBitrate int
+}
+
+// PlayDecision estimates whether a client with profile could direct play media, and the reason when
+// it could not. It only compares codecs, container, resolution and bitrate: the server also considers
+// streams, subtitles and the client's settings, and may decide differently.
+func PlayDecision(media Media, profile ClientProfile) (PlaybackDecision, string) {
+	if !profileSupports(profile.VideoCodecs, media.VideoCodec) {
+		return DecisionTranscode, fmt.Sprintf("video codec %s is not supported", media.VideoCodec)
+	}
+
+	if profile.MaxHeight > 0 && media.Height > profile.MaxHeight {
+		return DecisionTranscode, fmt.Sprintf("resolution %dp is higher than %dp", media.Height, profile.MaxHeight)
+	}
+
+	if profile.MaxBitrate > 0 && media.Bitrate > profile.MaxBitrate {
+		return DecisionTranscode, fmt.Sprintf("bitrate of %d kbps is higher than %d kbps", media.Bitrate, profile.MaxBitrate)
+	}
+
+	if !profileSupports(profile.AudioCodecs, media.AudioCodec) {
+		return DecisionDirectStream, fmt.Sprintf("audio codec %s is not supported", media.AudioCodec)
+	}
+
+	if !profileSupports(profile.Containers, media.Container) {
+		return DecisionDirectStream, fmt.Sprintf("container %s is not supported", media.Container)
+	}
+
+	return DecisionDirectPlay, ""
+}
+
+// profileSupports reports whether value is in supported, which allows anything when empty.
+// Media without the value (i.e. no audio) is always supported.
+func profileSupports(supported []string, value string) bool {
+	if len(supported) == 0 || value == "" {
+		return true
+	}
+
+	for _, s := range supported {
+		if strings.EqualFold(s, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,37 @@
+package plex
+
+import "testing"
+
+func TestPlayDecision(t *testing.T) {
+	profile := ClientProfile{
+		Containers:  []string{"mp4"},
+		VideoCodecs: []string{"h264"},
+		AudioCodecs: []string{"aac", "ac3"},
+		MaxHeight:   1080,
+		MaxBitrate:  20000,
+	}
+
+	tests := []struct {
+		media    Media
+		decision PlaybackDecision
+	}{
+		{Media{Container: "mp4", VideoCodec: "h264", AudioCodec: "aac", Height: 1080, Bitrate: 8000}, DecisionDirectPlay},
+		{Media{Container: "mkv", VideoCodec: "H264", AudioCodec: "ac3", Height: 720, Bitrate: 4000}, DecisionDirectStream},
+		{Media{Container: "mp4", VideoCodec: "h264", AudioCodec: "dca", Height: 1080, Bitrate: 8000}, DecisionDirectStream},
+		{Media{Container: "mkv", VideoCodec: "hevc", AudioCodec: "aac", Height: 1080, Bitrate: 8000}, DecisionTranscode},
+		{Media{Container: "mp4", VideoCodec: "h264", AudioCodec: "aac", Height: 2160, Bitrate: 8000}, DecisionTranscode},
+		{Media{Container: "mp4", VideoCodec: "h264", AudioCodec: "aac", Height: 1080, Bitrate: 40000}, DecisionTranscode},
+	}
+
+	for _, test := range tests {
+		decision, reason := PlayDecision(test.media, profile)
+
+		if decision != test.decision {
+			t.Errorf("%+v: Expected: %s\n Got: %s (%s)", test.media, test.decision, decision, reason)
+		}
+
+		if decision != DecisionDirectPlay && reason == "" {
+			t.Errorf("%+v: Expected: a reason for %s", test.media, decision)
+		}
+	}
+}
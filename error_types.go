@@ -1,5 +1,7 @@
 package plex
 
+import "errors"
+
 // ErrorInvalidToken a constant to help check invalid token errors
 const (
 	ErrorInvalidToken       = "invalid token"
@@ -14,4 +16,50 @@ const (
 	ErrorPINNotAuthorized   = "pin is not authorized yet"
 	ErrorLinkAccount        = "failed to link account: %s"
 	ErrorFailedToSetWebhook = "failed to set webhook"
+	ErrorInvalidPIN         = "invalid pin"
+	ErrorPINRequired        = "a pin is required to switch to this user"
+	ErrorUnknownButlerTask  = "unknown butler task"
+	ErrorButlerTaskRunning  = "butler task is already running"
+	ErrorRangeNotSupported  = "server does not support resuming downloads"
+	ErrorDeviceNotFound     = "device not found"
+	ErrorDeviceNotOwned     = "device belongs to another account"
+	ErrorSectionNotFound    = "library section not found"
+	ErrorSessionNotFound    = "session not found"
+	ErrorServerNotFound     = "server not found"
+	ErrorActivityNotFound   = "activity not found, it may have finished"
 )
+
+// ErrUnknownButlerTask is returned by RunButlerTask when the server has no task with the name
+var ErrUnknownButlerTask = errors.New(ErrorUnknownButlerTask)
+
+// ErrButlerTaskRunning is returned by RunButlerTask when the task is already running
+var ErrButlerTaskRunning = errors.New(ErrorButlerTaskRunning)
+
+// ErrRangeNotSupported is returned by DownloadMedia when the server can not resume a download from
+// an offset, in which case the download can be started over
+var ErrRangeNotSupported = errors.New(ErrorRangeNotSupported)
+
+// ErrSectionNotFound is returned by GetSectionByTitle when no library section has the title
+var ErrSectionNotFound = errors.New(ErrorSectionNotFound)
+
+// ErrSessionNotFound is returned by SessionCache.Lookup when the server has no session with the key
+var ErrSessionNotFound = errors.New(ErrorSessionNotFound)
+
+// ErrServerNotFound is returned by ResolveServerName when none of your servers has the machine identifier
+var ErrServerNotFound = errors.New(ErrorServerNotFound)
+
+// ErrDeviceNotFound is returned by RemoveDevice when your account has no device with the id
+var ErrDeviceNotFound = errors.New(ErrorDeviceNotFound)
+
+// ErrDeviceNotOwned is returned by RemoveDevice when the device belongs to another account
+var ErrDeviceNotOwned = errors.New(ErrorDeviceNotOwned)
+
+// ErrPINRequired is returned by SwitchHomeUser when the user is protected by a pin and none was given
+var ErrPINRequired = errors.New(ErrorPINRequired)
+
+// ErrInvalidPIN is returned by SwitchHomeUser when the pin of the user is wrong
+var ErrInvalidPIN = errors.New(ErrorInvalidPIN)
+
+// ErrActivityNotFound is returned by CancelActivity when the server has no activity with the uuid,
+// i.e. when it already finished
+var ErrActivityNotFound = errors.New(ErrorActivityNotFound)
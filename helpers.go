@@ -1,6 +1,13 @@
 package plex
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
 
 // GetMediaTypeID returns plex's media type id
 func GetMediaTypeID(mediaType string) string {
@@ -40,6 +47,58 @@ func GetMediaTypeID(mediaType string) string {
 	}
 }
 
+// String returns the name of the media type (i.e. movie), as accepted by GetMediaTypeID
+func (t MediaType) String() string {
+	switch t {
+	case MediaTypeMovie:
+		return "movie"
+	case MediaTypeShow:
+		return "show"
+	case MediaTypeSeason:
+		return "season"
+	case MediaTypeEpisode:
+		return "episode"
+	case MediaTypeTrailer:
+		return "trailer"
+	case MediaTypeComic:
+		return "comic"
+	case MediaTypePerson:
+		return "person"
+	case MediaTypeArtist:
+		return "artist"
+	case MediaTypeAlbum:
+		return "album"
+	case MediaTypeTrack:
+		return "track"
+	case MediaTypePhotoAlbum:
+		return "photoAlbum"
+	case MediaTypePicture:
+		return "picture"
+	case MediaTypePhoto:
+		return "photo"
+	case MediaTypeClip:
+		return "clip"
+	case MediaTypePlaylistItem:
+		return "playlistItem"
+	default:
+		return "MediaType(" + strconv.Itoa(int(t)) + ")"
+	}
+}
+
+// String returns the name of the stream type (i.e. audio)
+func (t StreamType) String() string {
+	switch t {
+	case StreamTypeVideo:
+		return "video"
+	case StreamTypeAudio:
+		return "audio"
+	case StreamTypeSubtitle:
+		return "subtitle"
+	default:
+		return "StreamType(" + strconv.Itoa(int(t)) + ")"
+	}
+}
+
 // GetMediaType is a helper function that returns the media type. Usually, used after GetMetadata().
 func GetMediaType(info MediaMetadata) string {
 	if dType := info.MediaContainer.Metadata[0].Type; dType != "" {
@@ -89,3 +148,627 @@ func LibraryParamsFromMediaType(mediaType string) (CreateLibraryParams, error) {
 		return params, errors.New("unknown library type")
 	}
 }
+
+// SharingFilter restricts a shared library to media matching (or not matching) labels and content ratings.
+// Use String() to pass it as a filter to InviteFriendParams or UpdateFriendParams
+type SharingFilter struct {
+	Labels                []string
+	ExcludeLabels         []string
+	ContentRatings        []string
+	ExcludeContentRatings []string
+}
+
+// String serializes the filter in the format plex expects (i.e. label=kids|contentRating!=R)
+func (f SharingFilter) String() string {
+	var filters []string
+
+	add := func(key string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+
+		escaped := make([]string, len(values))
+
+		for ii, value := range values {
+			escaped[ii] = url.QueryEscape(value)
+		}
+
+		filters = append(filters, key+"="+strings.Join(escaped, "%2C"))
+	}
+
+	add("contentRating", f.ContentRatings)
+	add("contentRating!", f.ExcludeContentRatings)
+	add("label", f.Labels)
+	add("label!", f.ExcludeLabels)
+
+	return strings.Join(filters, "|")
+}
+
+// ParseSharingFilter parses a filter as returned by plex (i.e. Friends.FilterMovies)
+func ParseSharingFilter(filter string) (SharingFilter, error) {
+	var f SharingFilter
+
+	if filter == "" {
+		return f, nil
+	}
+
+	for _, condition := range strings.Split(filter, "|") {
+		split := strings.SplitN(condition, "=", 2)
+
+		if len(split) != 2 {
+			return f, fmt.Errorf("invalid sharing filter: %s", condition)
+		}
+
+		var values []string
+
+		for _, value := range strings.Split(split[1], "%2C") {
+			unescaped, err := url.QueryUnescape(value)
+
+			if err != nil {
+				return f, err
+			}
+
+			values = append(values, unescaped)
+		}
+
+		switch split[0] {
+		case "contentRating":
+			f.ContentRatings = append(f.ContentRatings, values...)
+		case "contentRating!":
+			f.ExcludeContentRatings = append(f.ExcludeContentRatings, values...)
+		case "label":
+			f.Labels = append(f.Labels, values...)
+		case "label!":
+			f.ExcludeLabels = append(f.ExcludeLabels, values...)
+		default:
+			return f, fmt.Errorf("invalid sharing filter: %s", condition)
+		}
+	}
+
+	return f, nil
+}
+
+// contentRatingLevel is a content rating and its level, the age it is suitable for, comparable across
+// the movie and tv rating systems
+type contentRatingLevel struct {
+	rating string
+	level  int
+}
+
+// movie and tv content ratings, from the least to the most restricted
+var (
+	movieContentRatings = []contentRatingLevel{{"G", 0}, {"PG", 1}, {"PG-13", 2}, {"R", 3}, {"NC-17", 4}}
+	tvContentRatings    = []contentRatingLevel{{"TV-Y", 0}, {"TV-G", 0}, {"TV-Y7", 1}, {"TV-PG", 1}, {"TV-14", 2}, {"TV-MA", 3}}
+)
+
+// ContentRatingFilters returns the sharing filters of movies and tv shows allowing content rated up to maxRating,
+// either a movie (i.e. PG-13) or a tv (i.e. TV-14) rating. The rating is translated to the other rating system,
+// PG-13 allowing up to TV-14 and TV-PG allowing up to PG. Unrated media is not allowed.
+//
+//	movies, television, err := ContentRatingFilters("PG-13")
+//	params := UpdateFriendParams{FilterMovies: movies.String(), FilterTelevision: television.String()}
+func ContentRatingFilters(maxRating string) (movies, television SharingFilter, err error) {
+	level := -1
+
+	for _, r := range append(movieContentRatings, tvContentRatings...) {
+		if strings.EqualFold(r.rating, maxRating) {
+			level = r.level
+			break
+		}
+	}
+
+	if level < 0 {
+		return movies, television, fmt.Errorf("unknown content rating: %s", maxRating)
+	}
+
+	movies.ContentRatings = contentRatingsUpTo(movieContentRatings, level)
+	television.ContentRatings = contentRatingsUpTo(tvContentRatings, level)
+
+	return movies, television, nil
+}
+
+func contentRatingsUpTo(ratings []contentRatingLevel, level int) []string {
+	var allowed []string
+
+	for _, r := range ratings {
+		if r.level <= level {
+			allowed = append(allowed, r.rating)
+		}
+	}
+
+	return allowed
+}
+
+// LibraryFilter is a single condition of a LibraryQuery, i.e. {Field: "year", Operator: ">=", Value: "2000"}
+type LibraryFilter struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// operators supported by plex's library filters
+var libraryFilterOperators = map[string]bool{
+	"=":   true,
+	"!=":  true,
+	"==":  true,
+	"!==": true,
+	"<":   true,
+	">":   true,
+	"<=":  true,
+	">=":  true,
+	"<<":  true,
+	">>":  true,
+	"<<=": true,
+	">>=": true,
+}
+
+// LibraryQuery filters and sorts the content of a library section. Use it with QueryLibrary, or pass
+// String() to GetLibraryContent
+//
+//	// unwatched 4k movies added in the last 30 days sorted by rating
+//	q := LibraryQuery{Type: "movie", Unwatched: true, Resolution: "4k", Sort: "rating:desc"}.
+//		Where("addedAt", ">>=", "-30d")
+type LibraryQuery struct {
+	// Type is the media type to return, i.e. movie or episode
+	Type          string
+	Unwatched     bool
+	Genre         string
+	ContentRating string
+	Resolution    string
+	Label         string
+	Filters       []LibraryFilter
+	// Sort is the field to sort by optionally followed by the direction, i.e. addedAt:desc
+	Sort string
+}
+
+// Where returns a copy of the query with an additional condition, i.e. Where("year", ">=", "2000")
+func (q LibraryQuery) Where(field, operator, value string) LibraryQuery {
+	filters := make([]LibraryFilter, len(q.Filters), len(q.Filters)+1)
+	copy(filters, q.Filters)
+
+	q.Filters = append(filters, LibraryFilter{Field: field, Operator: operator, Value: value})
+
+	return q
+}
+
+// Validate checks that every condition uses an operator plex understands
+func (q LibraryQuery) Validate() error {
+	for _, filter := range q.Filters {
+		if filter.Field == "" {
+			return errors.New("library filter is missing a field")
+		}
+
+		if !libraryFilterOperators[filter.Operator] {
+			return fmt.Errorf("invalid library filter operator: %s", filter.Operator)
+		}
+	}
+
+	if q.Sort != "" {
+		return validateSort(q.Sort)
+	}
+
+	return nil
+}
+
+// validateSort checks a sort of the form field[:asc|:desc], i.e. addedAt:desc. Several sorts are separated by commas
+func validateSort(sort string) error {
+	for _, s := range strings.Split(sort, ",") {
+		field, direction := s, ""
+
+		if i := strings.Index(s, ":"); i >= 0 {
+			field, direction = s[:i], s[i+1:]
+
+			if direction != "asc" && direction != "desc" {
+				return fmt.Errorf("invalid sort direction in %s, must be asc or desc", sort)
+			}
+		}
+
+		if field == "" || strings.ContainsAny(field, " &=?") {
+			return fmt.Errorf("invalid sort field in %s", sort)
+		}
+	}
+
+	return nil
+}
+
+// String serializes the query into the query string of /library/sections/{key}/all (i.e. ?type=1&year>=2000)
+func (q LibraryQuery) String() string {
+	var params []string
+
+	add := func(field, operator, value string) {
+		params = append(params, url.QueryEscape(field)+operator+url.QueryEscape(value))
+	}
+
+	if q.Type != "" {
+		add("type", "=", GetMediaTypeID(q.Type))
+	}
+
+	if q.Unwatched {
+		add("unwatched", "=", "1")
+	}
+
+	if q.Genre != "" {
+		add("genre", "=", q.Genre)
+	}
+
+	if q.ContentRating != "" {
+		add("contentRating", "=", q.ContentRating)
+	}
+
+	if q.Resolution != "" {
+		add("resolution", "=", q.Resolution)
+	}
+
+	if q.Label != "" {
+		add("label", "=", q.Label)
+	}
+
+	for _, filter := range q.Filters {
+		add(filter.Field, filter.Operator, filter.Value)
+	}
+
+	if q.Sort != "" {
+		add("sort", "=", q.Sort)
+	}
+
+	if len(params) == 0 {
+		return ""
+	}
+
+	return "?" + strings.Join(params, "&")
+}
+
+// progressWriter reports the number of bytes written to w after every write
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress func(bytesWritten, totalBytes int64)
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+
+	pw.written += int64(n)
+	pw.progress(pw.written, pw.total)
+
+	return n, err
+}
+
+// VideoStreams returns the video streams of a part
+func (p Part) VideoStreams() []Stream {
+	return p.streamsOfType(StreamTypeVideo)
+}
+
+// AudioStreams returns the audio streams of a part
+func (p Part) AudioStreams() []Stream {
+	return p.streamsOfType(StreamTypeAudio)
+}
+
+// SubtitleStreams returns the subtitle streams of a part
+func (p Part) SubtitleStreams() []Stream {
+	return p.streamsOfType(StreamTypeSubtitle)
+}
+
+func (p Part) streamsOfType(streamType StreamType) []Stream {
+	var streams []Stream
+
+	for _, stream := range p.Stream {
+		if StreamType(stream.StreamType) == streamType {
+			streams = append(streams, stream)
+		}
+	}
+
+	return streams
+}
+
+// boolish is a bool that plex may encode as true/false, 1/0 or "1"/"0"
+type boolish bool
+
+func (b *boolish) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true", "1":
+		*b = true
+	case "false", "0", "", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean: %s", data)
+	}
+
+	return nil
+}
+
+// IsTranscoding reports whether the video or audio of the session is transcoded. Direct streams,
+// where only the container changes, are not transcoding.
+func (m MetadataV1) IsTranscoding() bool {
+	if t := m.TranscodeSession; t != nil {
+		return t.VideoDecision == "transcode" || t.AudioDecision == "transcode"
+	}
+
+	// without a transcode session, only the decision of the parts is known
+	for _, media := range m.Media {
+		for _, part := range media.Part {
+			if part.Decision == "transcode" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Normalize converts the session metadata to a Metadata
+func (m MetadataV1) Normalize() Metadata {
+	metadata := m.Metadata
+
+	metadata.Index = m.Index
+	metadata.ParentIndex = m.ParentIndex
+	metadata.AddedAt = int(m.AddedAt)
+	metadata.Duration = int(m.Duration)
+	metadata.LastViewedAt = int(m.LastViewedAt.epoch())
+	metadata.LibrarySectionID, _ = strconv.Atoi(m.LibrarySectionID)
+	metadata.Rating = m.Rating
+	metadata.UpdatedAt = int(m.UpdatedAt.epoch())
+	metadata.ViewOffset = int(m.ViewOffset)
+	metadata.Year = m.Year
+
+	metadata.Media = make([]Media, len(m.Media))
+
+	for ii, media := range m.Media {
+		metadata.Media[ii] = media.Normalize()
+	}
+
+	return metadata
+}
+
+// Normalize converts the session media to a Media
+func (m MediaV1) Normalize() Media {
+	media := m.Media
+
+	media.AudioChannels = m.AudioChannels
+	media.AspectRatio = strconv.FormatFloat(float64(m.AspectRatio), 'f', -1, 32)
+	media.Bitrate = m.Bitrate
+	media.Duration = int(m.Duration)
+	media.Has64bitOffsets = m.Has64bitOffsets
+	media.Height = m.Height
+	media.ID = int(m.ID)
+	media.OptimizedForStreaming = 0
+	media.Width = m.Width
+
+	if m.OptimizedForStreaming {
+		media.OptimizedForStreaming = 1
+	}
+
+	media.Part = make([]Part, len(m.Part))
+
+	for ii, part := range m.Part {
+		media.Part[ii] = part.Normalize()
+	}
+
+	return media
+}
+
+// Normalize converts the session part to a Part
+func (p PartV1) Normalize() Part {
+	part := p.Part
+
+	part.Duration = p.Duration
+	part.Has64bitOffsets = p.Has64bitOffsets
+	part.ID = int(p.ID)
+	part.OptimizedForStreaming = p.OptimizedForStreaming
+	part.Size = p.Size
+
+	part.Stream = make([]Stream, len(p.Stream))
+
+	for ii, stream := range p.Stream {
+		part.Stream[ii] = stream.Normalize()
+	}
+
+	return part
+}
+
+// Normalize converts the session stream to a Stream
+func (s StreamV1) Normalize() Stream {
+	stream := s.Stream
+
+	stream.BitDepth = s.BitDepth
+	stream.Default = s.Default
+	stream.Bitrate = s.Bitrate
+	stream.FrameRate = s.FrameRate
+	stream.HasScalingMatrix = s.HasScalingMatrix
+	stream.Height = s.Height
+	stream.Width = s.Width
+	stream.ID = int(s.ID)
+	stream.Index = s.Index
+	stream.Level = s.Level
+	stream.RefFrames = s.RefFrames
+	stream.StreamType = s.StreamType
+	stream.Channels = s.Channels
+	stream.SamplingRate = s.SamplingRate
+	stream.Selected = s.Selected
+
+	return stream
+}
+
+// PlexDirectURL returns the https url of a connection to a server using its plex.direct hostname
+// (i.e. https://192-168-1-10.{hash}.plex.direct:32400) which matches the server's certificate.
+// The hash is taken from the uris of the device's connections, as returned by GetServers.
+func PlexDirectURL(device PMSDevices, conn Connection) (string, error) {
+	var hash string
+
+	for _, c := range device.Connection {
+		u, err := url.Parse(c.URI)
+
+		if err != nil {
+			continue
+		}
+
+		labels := strings.Split(u.Hostname(), ".")
+
+		if len(labels) == 4 && labels[2] == "plex" && labels[3] == "direct" {
+			hash = labels[1]
+			break
+		}
+	}
+
+	if hash == "" {
+		return "", fmt.Errorf("no plex.direct connection found for %s", device.Name)
+	}
+
+	address := conn.Address
+
+	if address == "" {
+		return "", errors.New("connection is missing its address")
+	}
+
+	// ipv6 addresses use dashes as well, i.e. fe80--1
+	address = strings.NewReplacer(".", "-", ":", "-").Replace(strings.Trim(address, "[]"))
+
+	port := conn.Port
+
+	if port == "" {
+		port = "32400"
+	}
+
+	return fmt.Sprintf("https://%s.%s.plex.direct:%s", address, hash, port), nil
+}
+
+// SelectedItem returns the item of the play queue being played
+func (q PlayQueue) SelectedItem() (Metadata, bool) {
+	return q.item(0)
+}
+
+// NextItem returns the item of the play queue after the one being played
+func (q PlayQueue) NextItem() (Metadata, bool) {
+	return q.item(1)
+}
+
+// item returns the item offset positions after the selected item
+func (q PlayQueue) item(offset int) (Metadata, bool) {
+	items := q.MediaContainer.Metadata
+
+	for ii, item := range items {
+		if item.PlayQueueItemID != q.MediaContainer.PlayQueueSelectedItemID {
+			continue
+		}
+
+		if ii+offset < len(items) {
+			return items[ii+offset], true
+		}
+
+		break
+	}
+
+	return Metadata{}, false
+}
+
+// HasCapability reports whether the client advertises a protocol capability, i.e. playback or navigation
+func (c Client) HasCapability(capability string) bool {
+	for _, advertised := range strings.Split(c.ProtocolCapabilities, ",") {
+		if advertised == capability {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasFeature reports whether the subscription of the account includes the feature id (i.e. "webhooks")
+// or whether the account has the entitlement id
+func (u UserPlexTV) HasFeature(id string) bool {
+	for _, feature := range u.Subscription.Feature {
+		if feature.ID == id {
+			return true
+		}
+	}
+
+	for _, entitlements := range u.Entitlements {
+		for _, entitlement := range entitlements.Entitlement {
+			if entitlement.ID == id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// HasPlexPass reports whether the account has an active plex pass subscription
+func (u UserPlexTV) HasPlexPass() bool {
+	return u.Subscription.Active
+}
+
+// HasFeature reports whether the subscription of the account includes the feature id (i.e. "webhooks")
+// or whether the account has the entitlement id
+func (s SignInResponse) HasFeature(id string) bool {
+	return UserPlexTV(s).HasFeature(id)
+}
+
+// HasPlexPass reports whether the account has an active plex pass subscription
+func (s SignInResponse) HasPlexPass() bool {
+	return UserPlexTV(s).HasPlexPass()
+}
+
+// HasFeature reports whether the subscription of the account includes the feature id (i.e. "webhooks")
+// or whether the account has the entitlement id
+func (u User) HasFeature(id string) bool {
+	for _, feature := range u.Subscription.Features {
+		if feature == id {
+			return true
+		}
+	}
+
+	for _, entitlement := range u.Entitlements {
+		if entitlement == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPlexPass reports whether the account has an active plex pass subscription
+func (u User) HasPlexPass() bool {
+	return u.Subscription.Active
+}
+
+// VideoBitrates returns the bitrates in kbps of the server's transcoder quality ladder, lowest first
+func (r BaseAPIResponse) VideoBitrates() ([]int, error) {
+	return splitInts(r.MediaContainer.TranscoderVideoBitrates)
+}
+
+// VideoQualities returns the qualities of the server's transcoder quality ladder, matching VideoBitrates
+func (r BaseAPIResponse) VideoQualities() ([]int, error) {
+	return splitInts(r.MediaContainer.TranscoderVideoQualities)
+}
+
+// VideoResolutions returns the resolutions (i.e. 720) of the server's transcoder quality ladder, matching VideoBitrates
+func (r BaseAPIResponse) VideoResolutions() []string {
+	if r.MediaContainer.TranscoderVideoResolutions == "" {
+		return nil
+	}
+
+	return strings.Split(r.MediaContainer.TranscoderVideoResolutions, ",")
+}
+
+// splitInts parses comma separated integers
+func splitInts(csv string) ([]int, error) {
+	if csv == "" {
+		return nil, nil
+	}
+
+	values := strings.Split(csv, ",")
+	ints := make([]int, len(values))
+
+	for ii, value := range values {
+		i, err := strconv.Atoi(strings.TrimSpace(value))
+
+		if err != nil {
+			return nil, err
+		}
+
+		ints[ii] = i
+	}
+
+	return ints, nil
+}
@@ -0,0 +1,343 @@
+package plex
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+)
+
+func TestSharingFilter(t *testing.T) {
+	filter := SharingFilter{
+		Labels:                []string{"kids"},
+		ExcludeLabels:         []string{"scary movies"},
+		ContentRatings:        []string{"G", "PG"},
+		ExcludeContentRatings: []string{"R"},
+	}
+
+	expected := "contentRating=G%2CPG|contentRating!=R|label=kids|label!=scary+movies"
+
+	if filter.String() != expected {
+		t.Errorf("Expected: %s\n Got: %s", expected, filter.String())
+		return
+	}
+
+	parsed, err := ParseSharingFilter(filter.String())
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if parsed.String() != expected {
+		t.Errorf("Expected: %s\n Got: %s", expected, parsed.String())
+	}
+
+	if _, err := ParseSharingFilter("year>=2000"); err == nil {
+		t.Error("expected an error for an unsupported filter")
+	}
+}
+
+func TestContentRatingFilters(t *testing.T) {
+	tests := []struct {
+		maxRating  string
+		movies     string
+		television string
+	}{
+		{"PG-13", "contentRating=G%2CPG%2CPG-13", "contentRating=TV-Y%2CTV-G%2CTV-Y7%2CTV-PG%2CTV-14"},
+		{"tv-pg", "contentRating=G%2CPG", "contentRating=TV-Y%2CTV-G%2CTV-Y7%2CTV-PG"},
+		{"G", "contentRating=G", "contentRating=TV-Y%2CTV-G"},
+	}
+
+	for _, test := range tests {
+		movies, television, err := ContentRatingFilters(test.maxRating)
+
+		if err != nil {
+			t.Error(err.Error())
+			continue
+		}
+
+		if movies.String() != test.movies || television.String() != test.television {
+			t.Errorf("%s: Expected: %s and %s\n Got: %s and %s", test.maxRating, test.movies, test.television, movies.String(), television.String())
+		}
+	}
+
+	if _, _, err := ContentRatingFilters("12A"); err == nil {
+		t.Error("expected an error for an unknown rating")
+	}
+}
+
+func TestPartStreams(t *testing.T) {
+	part := Part{
+		Stream: []Stream{
+			{ID: 1, StreamType: int(StreamTypeVideo), DisplayTitle: "4K (HEVC Main 10)"},
+			{ID: 2, StreamType: int(StreamTypeAudio), DisplayTitle: "English (TRUEHD 7.1)", Language: "English"},
+			{ID: 3, StreamType: int(StreamTypeAudio), DisplayTitle: "Français (AC3 5.1)", Language: "Français"},
+			{ID: 4, StreamType: int(StreamTypeSubtitle), DisplayTitle: "English (SRT)", Language: "English"},
+		},
+	}
+
+	if got := len(part.VideoStreams()); got != 1 {
+		t.Errorf("Expected: 1 video stream\n Got: %d", got)
+	}
+
+	audio := part.AudioStreams()
+
+	if len(audio) != 2 || audio[0].ID != 2 || audio[1].ID != 3 {
+		t.Errorf("Expected: audio streams 2 and 3\n Got: %v", audio)
+	}
+
+	subtitles := part.SubtitleStreams()
+
+	if len(subtitles) != 1 || subtitles[0].Language != "English" {
+		t.Errorf("Expected: 1 english subtitle stream\n Got: %v", subtitles)
+	}
+}
+
+func TestLibraryQuery(t *testing.T) {
+	q := LibraryQuery{Type: "movie", Unwatched: true, Resolution: "4k", Sort: "rating:desc"}.
+		Where("addedAt", ">>=", "-30d").
+		Where("year", ">=", "2000")
+
+	expected := "?type=1&unwatched=1&resolution=4k&addedAt>>=-30d&year>=2000&sort=rating%3Adesc"
+
+	if got := q.String(); got != expected {
+		t.Errorf("Expected: %s\n Got: %s", expected, got)
+	}
+
+	if err := q.Validate(); err != nil {
+		t.Error(err.Error())
+	}
+
+	if err := q.Where("year", "=>", "2000").Validate(); err == nil {
+		t.Error("Expected: invalid operator error\n Got: nil")
+	}
+
+	if len(q.Filters) != 2 {
+		t.Errorf("Expected: Where to not modify the query\n Got: %d filters", len(q.Filters))
+	}
+
+	if got := (LibraryQuery{Genre: "Science Fiction"}).String(); got != "?genre=Science+Fiction" {
+		t.Errorf("Expected: %s\n Got: %s", "?genre=Science+Fiction", got)
+	}
+}
+
+func TestValidateSort(t *testing.T) {
+	valid := []string{"addedAt", "addedAt:desc", "titleSort:asc", "rating:desc,titleSort"}
+	invalid := []string{":desc", "addedAt:down", "addedAt:", "title sort:asc", "rating:desc,"}
+
+	for _, sort := range valid {
+		if err := validateSort(sort); err != nil {
+			t.Errorf("%s: %v", sort, err)
+		}
+	}
+
+	for _, sort := range invalid {
+		if err := validateSort(sort); err == nil {
+			t.Errorf("%s: Expected: an error\n Got: nil", sort)
+		}
+	}
+}
+
+func TestMetadataV1Normalize(t *testing.T) {
+	testData := []byte(`{"MediaContainer":{"size":1,"Metadata":[{"ratingKey":"10","title":"Pilot","librarySectionID":"2","addedAt":1600000000,"lastViewedAt":1700000000,"viewOffset":5000,"Media":[{"id":"3","aspectRatio":"1.78","optimizedForStreaming":true,"Part":[{"id":"4","key":"/library/parts/4/file.mkv","Stream":[{"id":"5","streamType":2,"displayTitle":"English (AAC Stereo)"}]}]}]}]}}`)
+
+	var sessions CurrentSessions
+
+	if err := json.Unmarshal(testData, &sessions); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	metadata := sessions.MediaContainer.Metadata[0].Normalize()
+
+	if metadata.RatingKey != "10" || metadata.LibrarySectionID != 2 || metadata.LastViewedAt != 1700000000 || metadata.ViewOffset != 5000 {
+		t.Errorf("Expected: normalized metadata\n Got: %+v", metadata)
+	}
+
+	media := metadata.Media[0]
+
+	if media.ID != 3 || media.AspectRatio != "1.78" || media.OptimizedForStreaming != 1 {
+		t.Errorf("Expected: normalized media\n Got: %+v", media)
+	}
+
+	part := media.Part[0]
+
+	if part.ID != 4 || part.Key != "/library/parts/4/file.mkv" {
+		t.Errorf("Expected: normalized part\n Got: %+v", part)
+	}
+
+	if streams := part.AudioStreams(); len(streams) != 1 || streams[0].ID != 5 {
+		t.Errorf("Expected: 1 audio stream with id 5\n Got: %v", streams)
+	}
+}
+
+func TestMetadataV1NormalizeMissingFields(t *testing.T) {
+	testData := []byte(`{"MediaContainer":{"size":2,"Metadata":[{"ratingKey":"10","Media":[{"id":"3","Part":[]}]},{"ratingKey":"11","Media":[{"id":"4","duration":5400000}]}]}}`)
+
+	var sessions CurrentSessions
+
+	if err := json.Unmarshal(testData, &sessions); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	metadata := sessions.MediaContainer.Metadata[0].Normalize()
+
+	if metadata.LastViewedAt != 0 || metadata.UpdatedAt != 0 || metadata.Media[0].Duration != 0 {
+		t.Errorf("Expected: 0 for the missing fields\n Got: %d, %d and %d", metadata.LastViewedAt, metadata.UpdatedAt, metadata.Media[0].Duration)
+	}
+
+	if !sessions.MediaContainer.Metadata[0].LastViewed().IsZero() {
+		t.Errorf("Expected: the zero time\n Got: %s", sessions.MediaContainer.Metadata[0].LastViewed())
+	}
+
+	if duration := sessions.MediaContainer.Metadata[1].Normalize().Media[0].Duration; duration != 5400000 {
+		t.Errorf("Expected: a duration of 5400000 ms\n Got: %d", duration)
+	}
+}
+
+func TestPlexDirectURL(t *testing.T) {
+	device := PMSDevices{
+		Name: "plex",
+		Connection: []Connection{
+			{Protocol: "https", Address: "192.168.1.10", Port: "32400", URI: "https://192-168-1-10.0123456789abcdef.plex.direct:32400", Local: 1},
+			{Protocol: "https", Address: "1.2.3.4", Port: "12345", URI: "https://1-2-3-4.0123456789abcdef.plex.direct:12345"},
+		},
+	}
+
+	got, err := PlexDirectURL(device, Connection{Address: "1.2.3.4", Port: "12345"})
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if expected := "https://1-2-3-4.0123456789abcdef.plex.direct:12345"; got != expected {
+		t.Errorf("Expected: %s\n Got: %s", expected, got)
+	}
+
+	if _, err := PlexDirectURL(PMSDevices{}, Connection{Address: "1.2.3.4"}); err == nil {
+		t.Error("Expected: an error without a plex.direct connection\n Got: nil")
+	}
+}
+
+func TestTypeStrings(t *testing.T) {
+	if got := MediaTypeEpisode.String(); got != "episode" {
+		t.Errorf("Expected: episode\n Got: %s", got)
+	}
+
+	if got := GetMediaTypeID(MediaTypePhotoAlbum.String()); got != "11" {
+		t.Errorf("Expected: 11\n Got: %s", got)
+	}
+
+	if got := StreamTypeSubtitle.String(); got != "subtitle" {
+		t.Errorf("Expected: subtitle\n Got: %s", got)
+	}
+
+	if got := StreamType(7).String(); got != "StreamType(7)" {
+		t.Errorf("Expected: StreamType(7)\n Got: %s", got)
+	}
+}
+
+func TestFlexibleNumbers(t *testing.T) {
+	data := []string{
+		`{"rating":7.5,"userRating":"8.0","audienceRating":"","index":"3","parentIndex":1,"viewCount":"2"}`,
+		`{"rating":"7.5","userRating":8,"audienceRating":null,"index":3,"parentIndex":"1","viewCount":2.0}`,
+	}
+
+	for _, d := range data {
+		var metadata Metadata
+
+		if err := json.Unmarshal([]byte(d), &metadata); err != nil {
+			t.Errorf("%s: %s", d, err.Error())
+			continue
+		}
+
+		if metadata.Rating != 7.5 || metadata.UserRating != 8 || metadata.AudienceRating != 0 {
+			t.Errorf("Expected: ratings 7.5, 8 and 0\n Got: %v, %v and %v", metadata.Rating, metadata.UserRating, metadata.AudienceRating)
+		}
+
+		if metadata.Index != 3 || metadata.ParentIndex != 1 || metadata.ViewCount != 2 {
+			t.Errorf("Expected: index 3, parent index 1 and view count 2\n Got: %d, %d and %d", metadata.Index, metadata.ParentIndex, metadata.ViewCount)
+		}
+	}
+}
+
+func TestHasFeature(t *testing.T) {
+	var xmlUser UserPlexTV
+
+	xmlData := `<user id="1" username="brw"><subscription active="1" status="Active" plan="lifetime"><feature id="webhooks"/><feature id="sync"/></subscription><entitlements><entitlement id="roku"/></entitlements></user>`
+
+	if err := xml.Unmarshal([]byte(xmlData), &xmlUser); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	var jsonUser User
+
+	jsonData := `{"id":"1","username":"brw","subscription":{"active":true,"features":["webhooks","sync"]},"entitlements":["roku"]}`
+
+	if err := json.Unmarshal([]byte(jsonData), &jsonUser); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	users := map[string]interface {
+		HasFeature(id string) bool
+		HasPlexPass() bool
+	}{
+		"xml":     xmlUser,
+		"sign in": SignInResponse(xmlUser),
+		"json":    jsonUser,
+	}
+
+	for shape, user := range users {
+		if !user.HasPlexPass() {
+			t.Errorf("%s: Expected: plex pass", shape)
+		}
+
+		if !user.HasFeature("webhooks") || !user.HasFeature("roku") {
+			t.Errorf("%s: Expected: the webhooks feature and roku entitlement", shape)
+		}
+
+		if user.HasFeature("cloudsync") {
+			t.Errorf("%s: Expected: no cloudsync feature", shape)
+		}
+	}
+}
+
+func TestTranscoderVideoLadder(t *testing.T) {
+	var info BaseAPIResponse
+
+	data := `{"MediaContainer":{"transcoderVideoBitrates":"64,96,208,320,720,1500,2000,3000,4000,8000,10000,12000,20000","transcoderVideoQualities":"0,7,15,30,43,60,60,75,100,60,75,90,100","transcoderVideoResolutions":"128,128,160,240,320,480,768,720,720,1080,1080,1080,1080"}}`
+
+	if err := json.Unmarshal([]byte(data), &info); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	bitrates, err := info.VideoBitrates()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	qualities, err := info.VideoQualities()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	resolutions := info.VideoResolutions()
+
+	if len(bitrates) != 13 || len(qualities) != 13 || len(resolutions) != 13 {
+		t.Errorf("Expected: 13 steps\n Got: %d bitrates, %d qualities and %d resolutions", len(bitrates), len(qualities), len(resolutions))
+		return
+	}
+
+	if bitrates[9] != 8000 || resolutions[9] != "1080" {
+		t.Errorf("Expected: 8000 kbps at 1080\n Got: %d kbps at %s", bitrates[9], resolutions[9])
+	}
+}
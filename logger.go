@@ -0,0 +1,21 @@
+package plex
+
+// Logger receives the messages logged by the client, i.e. unknown websocket events.
+// *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// noopLogger discards every message
+type noopLogger struct{}
+
+func (noopLogger) Printf(format string, v ...interface{}) {}
+
+// orNoop returns l, or a logger discarding every message when l is nil
+func orNoop(l Logger) Logger {
+	if l == nil {
+		return noopLogger{}
+	}
+
+	return l
+}
@@ -1,6 +1,7 @@
 package plex
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"net/http"
@@ -11,12 +12,29 @@ import (
 // Plex contains fields that are required to make
 // an api call to your plex server
 type Plex struct {
-	URL              string
-	Token            string
+	URL   string
+	Token string
+	// ClientIdentifier identifies your application to plex and should stay the same across sessions,
+	// see GenerateClientIdentifier
 	ClientIdentifier string
 	Headers          headers
-	HTTPClient       http.Client
-	DownloadClient   http.Client
+	// PlexTVURL overrides the base url of plex.tv requests, including discover requests, i.e. to use
+	// a mock server or a proxy. DefaultPlexTVURL is used when it is empty, see WithPlexTVURL.
+	PlexTVURL      string
+	HTTPClient     http.Client
+	DownloadClient http.Client
+	// Logger receives the client's internal log messages. Nothing is logged when it is nil.
+	Logger Logger
+	// Retry configures retries of failed GET requests. Requests are not retried by default.
+	Retry RetryPolicy
+	// ResponseHook is called with every response of the api before it is decoded, i.e. to log the
+	// body when decoding fails. Downloads are not passed to it.
+	ResponseHook func(resp *http.Response)
+
+	limiter     *rateLimiter
+	serverNames *serverNameCache
+	// ctx cancels requests, see WithContext
+	ctx context.Context
 }
 
 // SearchResults a list of media returned when searching
@@ -42,57 +60,115 @@ type SearchResults struct {
 
 // Metadata ...
 type Metadata struct {
-	Player                Player       `json:"Player"`
-	Session               Session      `json:"Session"`
-	User                  User         `json:"User"`
-	AddedAt               int          `json:"addedAt"`
-	Art                   string       `json:"art"`
-	AudienceRating        float64      `json:"audienceRating"`
-	AudienceRatingImage   string       `json:"audienceRatingImage"`
-	ContentRating         string       `json:"contentRating"`
-	Duration              int          `json:"duration"`
-	GrandparentArt        string       `json:"grandparentArt"`
-	GrandparentGUID       string       `json:"grandparentGuid"`
-	GrandparentKey        string       `json:"grandparentKey"`
-	GrandparentRatingKey  string       `json:"grandparentRatingKey"`
-	GrandparentTheme      string       `json:"grandparentTheme"`
-	GrandparentThumb      string       `json:"grandparentThumb"`
-	GrandparentTitle      string       `json:"grandparentTitle"`
-	GUID                  string       `json:"guid"`
-	AltGUIDs              []AltGUID    `json:"Guid"`
-	Index                 int64        `json:"index"`
-	Key                   string       `json:"key"`
-	LastViewedAt          int          `json:"lastViewedAt"`
-	LibrarySectionID      int          `json:"librarySectionID"`
-	LibrarySectionKey     string       `json:"librarySectionKey"`
-	LibrarySectionTitle   string       `json:"librarySectionTitle"`
-	Live                  string       `json:"live"`
-	OriginallyAvailableAt string       `json:"originallyAvailableAt"`
-	OriginalTitle         string       `json:"originalTitle"`
-	ParentGUID            string       `json:"parentGuid"`
-	ParentIndex           int64        `json:"parentIndex"`
-	ParentKey             string       `json:"parentKey"`
-	ParentRatingKey       string       `json:"parentRatingKey"`
-	ParentThumb           string       `json:"parentThumb"`
-	ParentTitle           string       `json:"parentTitle"`
-	RatingCount           int          `json:"ratingCount"`
-	Ratings               []Rating     `json:"Rating"`
-	Rating                float64      `json:"rating"`
-	RatingKey             string       `json:"ratingKey"`
-	SessionKey            string       `json:"sessionKey"`
-	Summary               string       `json:"summary"`
-	Thumb                 string       `json:"thumb"`
-	Media                 []Media      `json:"Media"`
-	Title                 string       `json:"title"`
-	TitleSort             string       `json:"titleSort"`
-	Type                  string       `json:"type"`
-	UpdatedAt             int          `json:"updatedAt"`
-	UserRating            float64      `json:"userRating,string"`
-	ViewCount             json.Number  `json:"viewCount"`
-	ViewOffset            int          `json:"viewOffset"`
-	Year                  int          `json:"year"`
-	Director              []TaggedData `json:"Director"`
-	Writer                []TaggedData `json:"Writer"`
+	Player                Player          `json:"Player"`
+	Session               Session         `json:"Session"`
+	User                  User            `json:"User"`
+	AddedAt               int             `json:"addedAt"`
+	Art                   string          `json:"art"`
+	AudienceRating        FlexibleFloat   `json:"audienceRating"`
+	AudienceRatingImage   string          `json:"audienceRatingImage"`
+	Banner                string          `json:"banner"`
+	Chapter               []Chapter       `json:"Chapter"`
+	ContentRating         string          `json:"contentRating"`
+	Duration              int             `json:"duration"`
+	ExtraType             int             `json:"extraType"`
+	GrandparentArt        string          `json:"grandparentArt"`
+	GrandparentGUID       string          `json:"grandparentGuid"`
+	GrandparentKey        string          `json:"grandparentKey"`
+	GrandparentRatingKey  string          `json:"grandparentRatingKey"`
+	GrandparentTheme      string          `json:"grandparentTheme"`
+	GrandparentThumb      string          `json:"grandparentThumb"`
+	GrandparentTitle      string          `json:"grandparentTitle"`
+	GUID                  string          `json:"guid"`
+	AltGUIDs              []AltGUID       `json:"Guid"`
+	Image                 []DiscoverImage `json:"Image"`
+	Index                 FlexibleInt     `json:"index"`
+	Key                   string          `json:"key"`
+	LastViewedAt          int             `json:"lastViewedAt"`
+	LibrarySectionID      int             `json:"librarySectionID"`
+	LibrarySectionKey     string          `json:"librarySectionKey"`
+	LibrarySectionTitle   string          `json:"librarySectionTitle"`
+	Live                  string          `json:"live"`
+	Marker                []Marker        `json:"Marker"`
+	OriginallyAvailableAt string          `json:"originallyAvailableAt"`
+	OriginalTitle         string          `json:"originalTitle"`
+	ParentGUID            string          `json:"parentGuid"`
+	ParentIndex           FlexibleInt     `json:"parentIndex"`
+	ParentKey             string          `json:"parentKey"`
+	ParentRatingKey       string          `json:"parentRatingKey"`
+	ParentThumb           string          `json:"parentThumb"`
+	ParentTitle           string          `json:"parentTitle"`
+	PlaylistItemID        int             `json:"playlistItemID"`
+	PlayQueueItemID       int             `json:"playQueueItemID"`
+	RatingCount           int             `json:"ratingCount"`
+	Ratings               []Rating        `json:"Rating"`
+	Rating                FlexibleFloat   `json:"rating"`
+	RatingKey             string          `json:"ratingKey"`
+	SessionKey            string          `json:"sessionKey"`
+	Subtype               string          `json:"subtype"`
+	Summary               string          `json:"summary"`
+	Theme                 string          `json:"theme"`
+	Thumb                 string          `json:"thumb"`
+	Media                 []Media         `json:"Media"`
+	Title                 string          `json:"title"`
+	TitleSort             string          `json:"titleSort"`
+	Type                  string          `json:"type"`
+	UpdatedAt             int             `json:"updatedAt"`
+	UserRating            FlexibleFloat   `json:"userRating"`
+	ViewCount             FlexibleInt     `json:"viewCount"`
+	ViewOffset            int             `json:"viewOffset"`
+	Year                  int             `json:"year"`
+	Director              []TaggedData    `json:"Director"`
+	Writer                []TaggedData    `json:"Writer"`
+}
+
+// MetadataInclude is optional data GetMetadata can include
+type MetadataInclude string
+
+// data GetMetadata can include
+const (
+	IncludeMarkers  MetadataInclude = "includeMarkers"
+	IncludeChapters MetadataInclude = "includeChapters"
+)
+
+// extra types of the ExtraType of extras, see GetExtras. The Subtype of an extra
+// names its type, i.e. trailer or deletedScene
+const (
+	ExtraTypeTrailer         = 1
+	ExtraTypeDeletedScene    = 2
+	ExtraTypeInterview       = 3
+	ExtraTypeMusicVideo      = 4
+	ExtraTypeBehindTheScenes = 5
+	ExtraTypeSceneOrSample   = 6
+	ExtraTypeFeaturette      = 10
+	ExtraTypeShort           = 11
+)
+
+// marker types
+const (
+	MarkerTypeIntro   = "intro"
+	MarkerTypeCredits = "credits"
+)
+
+// Marker is the range of an intro or the credits of media, see GetMetadata and IncludeMarkers.
+// Offsets are in milliseconds.
+type Marker struct {
+	EndTimeOffset int64 `json:"endTimeOffset"`
+	// Final is set on the credits marker that lasts until the end of the media
+	Final           bool   `json:"final"`
+	ID              int    `json:"id"`
+	StartTimeOffset int64  `json:"startTimeOffset"`
+	Type            string `json:"type"`
+}
+
+// Chapter is a chapter of media, see GetMetadata and IncludeChapters. Offsets are in milliseconds.
+type Chapter struct {
+	EndTimeOffset   int64  `json:"endTimeOffset"`
+	ID              int    `json:"id"`
+	Index           int    `json:"index"`
+	StartTimeOffset int64  `json:"startTimeOffset"`
+	Tag             string `json:"tag"`
+	Thumb           string `json:"thumb"`
 }
 
 // AltGUID represents a Globally Unique Identifier for a metadata provider that is not actively being used.
@@ -100,20 +176,24 @@ type AltGUID struct {
 	ID string `json:"id"`
 }
 
-// MetadataV1 ...
+// MetadataV1 is the metadata of a session as returned by GetSessions (/status/sessions). Some of its
+// fields are encoded differently than Metadata, which every other endpoint returns. Use Normalize to
+// convert it to a Metadata.
 type MetadataV1 struct {
 	Metadata
-	Index            int64     `json:"index"`
-	ParentIndex      int64     `json:"parentIndex"`
-	AddedAt          int64     `json:"addedAt"`
-	Duration         int64     `json:"duration"`
-	LastViewedAt     Timestamp `json:"lastViewedAt"`
-	LibrarySectionID string    `json:"librarySectionID"`
-	Media            []MediaV1 `json:"Media"`
-	Rating           float64   `json:"rating"`
-	UpdatedAt        Timestamp `json:"updatedAt"`
-	ViewOffset       int64     `json:"viewOffset"`
-	Year             int       `json:"year"`
+	Index            FlexibleInt   `json:"index"`
+	ParentIndex      FlexibleInt   `json:"parentIndex"`
+	AddedAt          int64         `json:"addedAt"`
+	Duration         int64         `json:"duration"`
+	LastViewedAt     Timestamp     `json:"lastViewedAt"`
+	LibrarySectionID string        `json:"librarySectionID"`
+	Media            []MediaV1     `json:"Media"`
+	Rating           FlexibleFloat `json:"rating"`
+	// TranscodeSession is nil when the media is direct played or direct streamed
+	TranscodeSession *TranscodeSession `json:"TranscodeSession"`
+	UpdatedAt        Timestamp         `json:"updatedAt"`
+	ViewOffset       int64             `json:"viewOffset"`
+	Year             int               `json:"year"`
 }
 
 // Media media info
@@ -139,19 +219,19 @@ type Media struct {
 	Part                  []Part `json:"Part"`
 }
 
-// MediaV1 media information version 1
+// MediaV1 media information version 1, part of MetadataV1. Use Normalize to convert it to a Media.
 type MediaV1 struct {
 	Media
-	Part                  []PartV1  `json:"Part"`
-	AudioChannels         int       `json:"audioChannels"`
-	AspectRatio           float32   `json:"aspectRatio,string"`
-	Bitrate               int       `json:"bitrate"`
-	Duration              Timestamp `json:"duration"`
-	Has64bitOffsets       bool      `json:"has64bitOffsets"`
-	Height                int       `json:"height"`
-	ID                    int       `json:"id,string"`
-	OptimizedForStreaming bool      `json:"optimizedForStreaming"`
-	Width                 int       `json:"width"`
+	Part                  []PartV1      `json:"Part"`
+	AudioChannels         int           `json:"audioChannels"`
+	AspectRatio           FlexibleFloat `json:"aspectRatio"`
+	Bitrate               int           `json:"bitrate"`
+	Duration              int64         `json:"duration"`
+	Has64bitOffsets       bool          `json:"has64bitOffsets"`
+	Height                int           `json:"height"`
+	ID                    FlexibleInt   `json:"id"`
+	OptimizedForStreaming bool          `json:"optimizedForStreaming"`
+	Width                 int           `json:"width"`
 }
 
 // MediaContainer contains media info
@@ -164,7 +244,10 @@ type MediaContainer struct {
 	LibrarySectionUUID  string     `json:"librarySectionUUID"`
 	MediaTagPrefix      string     `json:"mediaTagPrefix"`
 	MediaTagVersion     int        `json:"mediaTagVersion"`
+	Offset              int        `json:"offset"`
 	Size                int        `json:"size"`
+	// TotalSize is the number of items matching the request when it was paged, see GetLibraryContentPage
+	TotalSize int `json:"totalSize"`
 }
 
 // MediaMetadata ...
@@ -219,6 +302,103 @@ type Role struct {
 	Thumb string `json:"thumb"`
 }
 
+// Playlist is a playlist on your plex server
+type Playlist struct {
+	AddedAt      int         `json:"addedAt"`
+	Composite    string      `json:"composite"`
+	Duration     int         `json:"duration"`
+	GUID         string      `json:"guid"`
+	Icon         string      `json:"icon"`
+	Key          string      `json:"key"`
+	LastViewedAt int         `json:"lastViewedAt"`
+	LeafCount    int         `json:"leafCount"`
+	PlaylistType string      `json:"playlistType"`
+	RatingKey    string      `json:"ratingKey"`
+	Smart        bool        `json:"smart"`
+	Summary      string      `json:"summary"`
+	Title        string      `json:"title"`
+	Type         string      `json:"type"`
+	UpdatedAt    int         `json:"updatedAt"`
+	ViewCount    FlexibleInt `json:"viewCount"`
+}
+
+// Playlists is the result of the /playlists endpoint
+type Playlists struct {
+	MediaContainer struct {
+		Metadata []Playlist `json:"Metadata"`
+		Size     int        `json:"size"`
+	} `json:"MediaContainer"`
+}
+
+// Collection is a group of media inside of a library section
+type Collection struct {
+	AddedAt          int    `json:"addedAt"`
+	Art              string `json:"art"`
+	ChildCount       string `json:"childCount"`
+	CollectionMode   string `json:"collectionMode"`
+	CollectionSort   string `json:"collectionSort"`
+	ContentRating    string `json:"contentRating"`
+	GUID             string `json:"guid"`
+	Key              string `json:"key"`
+	LibrarySectionID int    `json:"librarySectionID"`
+	MaxYear          string `json:"maxYear"`
+	MinYear          string `json:"minYear"`
+	RatingKey        string `json:"ratingKey"`
+	Subtype          string `json:"subtype"`
+	Summary          string `json:"summary"`
+	Thumb            string `json:"thumb"`
+	Title            string `json:"title"`
+	TitleSort        string `json:"titleSort"`
+	Type             string `json:"type"`
+	UpdatedAt        int    `json:"updatedAt"`
+}
+
+// Collection modes control how a collection is shown inside of its library
+const (
+	CollectionModeDefault   = "-1"
+	CollectionModeHide      = "0"
+	CollectionModeHideItems = "1"
+	CollectionModeShowItems = "2"
+)
+
+// Collection sorts control the order of the items inside of a collection
+const (
+	CollectionSortRelease      = "0"
+	CollectionSortAlphabetical = "1"
+)
+
+// Collections is the result of the /library/sections/{key}/collections endpoint
+type Collections struct {
+	MediaContainer struct {
+		Metadata []Collection `json:"Metadata"`
+		Size     int          `json:"size"`
+	} `json:"MediaContainer"`
+}
+
+// Hub is a group of media of the same kind (i.e. movies, episodes or actors)
+type Hub struct {
+	Context       string     `json:"context"`
+	HubIdentifier string     `json:"hubIdentifier"`
+	HubKey        string     `json:"hubKey"`
+	Key           string     `json:"key"`
+	More          bool       `json:"more"`
+	Promoted      bool       `json:"promoted"`
+	Size          int        `json:"size"`
+	Style         string     `json:"style"`
+	Title         string     `json:"title"`
+	Type          string     `json:"type"`
+	Metadata      []Metadata `json:"Metadata"`
+	Directory     []Metadata `json:"Directory"`
+}
+
+// Hubs is the result of the /hubs endpoints
+type Hubs struct {
+	MediaContainer struct {
+		Hub  []Hub `json:"Hub"`
+		Size int   `json:"size"`
+	} `json:"MediaContainer"`
+}
+
 // MetadataChildren returns metadata about a piece of media (tv show, movie, music, etc)
 type MetadataChildren struct {
 	MediaContainer MediaContainer `json:"MediaContainer"`
@@ -270,26 +450,8 @@ type plexResponse struct {
 }
 
 type killTranscodeResponse struct {
-	Children []struct {
-		ElementType   string  `json:"_elementType"`
-		AudioChannels int     `json:"audioChannels"`
-		AudioCodec    string  `json:"audioCodec"`
-		AudioDecision string  `json:"audioDecision"`
-		Container     string  `json:"container"`
-		Context       string  `json:"context"`
-		Duration      int     `json:"duration"`
-		Height        int     `json:"height"`
-		Key           string  `json:"key"`
-		Progress      float64 `json:"progress"`
-		Protocol      string  `json:"protocol"`
-		Remaining     int     `json:"remaining"`
-		Speed         float64 `json:"speed"`
-		Throttled     bool    `json:"throttled"`
-		VideoCodec    string  `json:"videoCodec"`
-		VideoDecision string  `json:"videoDecision"`
-		Width         int     `json:"width"`
-	} `json:"_children"`
-	ElementType string `json:"_elementType"`
+	Children    []LegacyTranscodeSession `json:"_children"`
+	ElementType string                   `json:"_elementType"`
 }
 
 // CreateLibraryParams params required to create a library
@@ -302,6 +464,149 @@ type CreateLibraryParams struct {
 	Language    string
 }
 
+type activitiesResponse struct {
+	MediaContainer struct {
+		Activity []Activity `json:"Activity"`
+	} `json:"MediaContainer"`
+}
+
+// Agent is a metadata agent of the server. Its Identifier is the Agent of CreateLibraryParams
+type Agent struct {
+	HasAttribution bool   `json:"hasAttribution"`
+	HasPrefs       bool   `json:"hasPrefs"`
+	Identifier     string `json:"identifier"`
+	Name           string `json:"name"`
+	Primary        bool   `json:"primary"`
+	MediaType      []struct {
+		MediaType int    `json:"mediaType"`
+		Name      string `json:"name"`
+	} `json:"MediaType"`
+}
+
+// Scanner is a library scanner of the server. Its Name is the Scanner of CreateLibraryParams
+type Scanner struct {
+	Name string `json:"name"`
+}
+
+type agentsResponse struct {
+	MediaContainer struct {
+		Agent []Agent `json:"Agent"`
+	} `json:"MediaContainer"`
+}
+
+type scannersResponse struct {
+	MediaContainer struct {
+		Scanner []Scanner `json:"Scanner"`
+	} `json:"MediaContainer"`
+}
+
+// MetadataEdit is the new value of a metadata field used by EditMetadata.
+// Locked prevents the metadata agent from overwriting the value
+type MetadataEdit struct {
+	Value  string
+	Locked bool
+}
+
+type settingsResponse struct {
+	MediaContainer struct {
+		Setting []Setting `json:"Setting"`
+		Size    int       `json:"size"`
+	} `json:"MediaContainer"`
+}
+
+// MappingStateMapped is the MappingState of a server reachable through its public address
+const MappingStateMapped = "mapped"
+
+// RemoteAccessState is whether and how the server is reachable from outside of its network, see GetRemoteAccessState
+type RemoteAccessState struct {
+	// Published is whether the server publishes itself to plex.tv for remote access
+	Published bool
+	// MappingState is MappingStateMapped when the public address reaches the server,
+	// i.e. unknown or failed otherwise, the reason being in MappingError
+	MappingState   string
+	MappingError   string
+	SignInState    string
+	PublicAddress  string
+	PublicPort     int
+	PrivateAddress string
+	PrivatePort    int
+}
+
+type myPlexAccountResponse struct {
+	MyPlex struct {
+		MappingError   string      `json:"mappingError"`
+		MappingState   string      `json:"mappingState"`
+		PrivateAddress string      `json:"privateAddress"`
+		PrivatePort    FlexibleInt `json:"privatePort"`
+		PublicAddress  string      `json:"publicAddress"`
+		PublicPort     FlexibleInt `json:"publicPort"`
+		SignInState    string      `json:"signInState"`
+	} `json:"MyPlex"`
+}
+
+// ButlerTask is a scheduled maintenance task of a plex server
+type ButlerTask struct {
+	Name               string `json:"name"`
+	Title              string `json:"title"`
+	Description        string `json:"description"`
+	Enabled            bool   `json:"enabled"`
+	Interval           int    `json:"interval"`
+	ScheduleRandomized bool   `json:"scheduleRandomized"`
+}
+
+// ButlerSchedule is the daily window in which butler tasks run, see GetButlerSchedule.
+// The window ends the next day when EndHour is lower than StartHour
+type ButlerSchedule struct {
+	StartHour int
+	EndHour   int
+	// Tasks is whether each scheduled task is enabled, by the Name of its ButlerTask, i.e. BackupDatabase
+	Tasks map[string]bool
+}
+
+type butlerTasksResponse struct {
+	ButlerTasks struct {
+		ButlerTask []ButlerTask `json:"ButlerTask"`
+	} `json:"ButlerTasks"`
+}
+
+// HistoryParams optional filters of GetHistory
+type HistoryParams struct {
+	AccountID   int
+	SectionKey  string
+	ViewedAfter time.Time
+}
+
+// HistoryEntry is a single play of a piece of media
+type HistoryEntry struct {
+	AccountID             int    `json:"accountID"`
+	DeviceID              int    `json:"deviceID"`
+	GrandparentArt        string `json:"grandparentArt"`
+	GrandparentKey        string `json:"grandparentKey"`
+	GrandparentThumb      string `json:"grandparentThumb"`
+	GrandparentTitle      string `json:"grandparentTitle"`
+	HistoryKey            string `json:"historyKey"`
+	Index                 int    `json:"index"`
+	Key                   string `json:"key"`
+	LibrarySectionID      string `json:"librarySectionID"`
+	OriginallyAvailableAt string `json:"originallyAvailableAt"`
+	ParentIndex           int    `json:"parentIndex"`
+	ParentKey             string `json:"parentKey"`
+	ParentThumb           string `json:"parentThumb"`
+	RatingKey             string `json:"ratingKey"`
+	Thumb                 string `json:"thumb"`
+	Title                 string `json:"title"`
+	Type                  string `json:"type"`
+	ViewedAt              int64  `json:"viewedAt"`
+}
+
+// History is the result of the /status/sessions/history/all endpoint
+type History struct {
+	MediaContainer struct {
+		Metadata []HistoryEntry `json:"Metadata"`
+		Size     int            `json:"size"`
+	} `json:"MediaContainer"`
+}
+
 // DevicesResponse  metadata of a device that has connected to your server
 type DevicesResponse struct {
 	ID         int    `json:"id"`
@@ -353,15 +658,24 @@ type friendsResponse struct {
 	User              []Friends `xml:"User"`
 }
 
+// plexTVErrorsResponse is the <errors><error code="..." message="..."/></errors> body of plex.tv errors
+type plexTVErrorsResponse struct {
+	XMLName xml.Name `xml:"errors"`
+	Error   []struct {
+		Code    int    `xml:"code,attr"`
+		Message string `xml:"message,attr"`
+	} `xml:"error"`
+}
+
+// resultResponse is the <Response code="0" status="..."/> body returned by the legacy plex.tv api
 type resultResponse struct {
-	XMLName  xml.Name `xml:"Response"`
-	Response struct {
-		Code   int    `xml:"code,attr"`
-		Status string `xml:"status,attr"`
-	} `xml:"Response"`
+	XMLName xml.Name `xml:"Response"`
+	Code    int      `xml:"code,attr"`
+	Status  string   `xml:"status,attr"`
 }
 
-type inviteFriendResponse struct {
+// SharedServer is an invitation to share a server with another plex user
+type SharedServer struct {
 	ID                json.Number `json:"id"`
 	Name              string      `json:"name"`
 	OwnerID           json.Number `json:"ownerId"`
@@ -386,34 +700,65 @@ type inviteFriendResponse struct {
 		Thumb      string      `json:"thumb"`
 		Status     string      `json:"status"`
 	} `json:"invited"`
-	SharingSettings struct {
-		AllowChannels    bool   `json:"allowChannels"`
-		FilterMovies     string `json:"filterMovies"`
-		FilterMusic      string `json:"filterMusic"`
-		FilterPhotos     string `json:"filterPhotos"`
-		FilterTelevision string `json:"filterTelevision"`
-		// FilterAll ??? I get null when testing. idk the true type
-		FilterAll          interface{} `json:"filterAll"`
-		AllowSync          bool        `json:"allowSync"`
-		AllowCameraUpload  bool        `json:"allowCameraUpload"`
-		AllowSubtitleAdmin bool        `json:"allowSubtitleAdmin"`
-		AllowTuners        json.Number `json:"allowTuners"`
-	} `json:"sharingSettings"`
-	Libraries []struct {
-		ID    json.Number `json:"id"`
-		Key   json.Number `json:"key"`
-		Title string      `json:"title"`
-		Type  string      `json:"type"`
-	} `json:"libraries"`
-	AllLibraries bool `json:"allLibraries"`
+	SharingSettings SharingSettings `json:"sharingSettings"`
+	Libraries       []SharedLibrary `json:"libraries"`
+	AllLibraries    bool            `json:"allLibraries"`
+}
+
+// SharingSettings are the restrictions of a user on a shared server. The filters can be parsed with ParseSharingFilter
+type SharingSettings struct {
+	AllowChannels    bool   `json:"allowChannels"`
+	FilterMovies     string `json:"filterMovies"`
+	FilterMusic      string `json:"filterMusic"`
+	FilterPhotos     string `json:"filterPhotos"`
+	FilterTelevision string `json:"filterTelevision"`
+	// FilterAll ??? I get null when testing. idk the true type
+	FilterAll          interface{} `json:"filterAll"`
+	AllowSync          bool        `json:"allowSync"`
+	AllowCameraUpload  bool        `json:"allowCameraUpload"`
+	AllowSubtitleAdmin bool        `json:"allowSubtitleAdmin"`
+	AllowTuners        json.Number `json:"allowTuners"`
+}
+
+// SharedLibrary is a library section of a shared server
+type SharedLibrary struct {
+	ID    json.Number `json:"id"`
+	Key   json.Number `json:"key"`
+	Title string      `json:"title"`
+	Type  string      `json:"type"`
+}
+
+// UserRestrictions are the restrictions of a user on one of your shared servers, see GetUserRestrictions
+type UserRestrictions struct {
+	MachineIdentifier string
+	SharingSettings
+	// AllLibraries is whether the user can access every library, otherwise only Libraries
+	AllLibraries bool
+	Libraries    []SharedLibrary
+	// Movies, Television and Music are the parsed filters of SharingSettings, i.e. the allowed content ratings
+	Movies     SharingFilter
+	Television SharingFilter
+	Music      SharingFilter
+}
+
+// Invites are the pending invitations you sent and received
+type Invites struct {
+	Sent     []SharedServer
+	Received []SharedServer
 }
 
 // InviteFriendParams are the params to invite a friend
+// Label is a shortcut that restricts movies and tv shows to media with that label.
+// The Filter fields take precedence over Label and accept SharingFilter.String() values
 type InviteFriendParams struct {
-	UsernameOrEmail string
-	MachineID       string
-	Label           string
-	LibraryIDs      []int
+	UsernameOrEmail  string
+	MachineID        string
+	Label            string
+	LibraryIDs       []int
+	FilterMovies     string
+	FilterTelevision string
+	FilterMusic      string
+	FilterPhotos     string
 }
 
 // UpdateFriendParams optional parameters to update your friends access to your server
@@ -438,6 +783,7 @@ type inviteFriendSettings struct {
 	AllowSync         string `json:"allowSync"`
 	FilterMovies      string `json:"filterMovies"`
 	FilterMusic       string `json:"filterMusic"`
+	FilterPhotos      string `json:"filterPhotos"`
 	FilterTelevision  string `json:"filterTelevision"`
 }
 
@@ -473,6 +819,9 @@ type PMSDevices struct {
 	PublicAddress        string       `json:"publicAddress" xml:"publicAddress,attr"`
 	Presence             string       `json:"presence" xml:"presence,attr"`
 	Connection           []Connection `json:"connection" xml:"Connection"`
+	// ID and Token are only returned by GetDevices
+	ID    int    `json:"id" xml:"id,attr"`
+	Token string `json:"token" xml:"token,attr"`
 }
 
 // Connection lists options to connect to a device
@@ -542,6 +891,80 @@ type BaseAPIResponse struct {
 	} `json:"MediaContainer"`
 }
 
+// ServerCapabilities describes a plex media server and the features it supports
+type ServerCapabilities struct {
+	AllowCameraUpload             bool
+	AllowChannelAccess            bool
+	AllowSharing                  bool
+	AllowSync                     bool
+	BackgroundProcessing          bool
+	Certificate                   bool
+	CompanionProxy                bool
+	EventStream                   bool
+	FriendlyName                  string
+	HubSearch                     bool
+	ItemClusters                  bool
+	MachineIdentifier             string
+	MediaProviders                bool
+	Multiuser                     bool
+	MyPlex                        bool
+	MyPlexSubscription            bool
+	MyPlexUsername                string
+	PhotoAutoTag                  bool
+	Platform                      string
+	PlatformVersion               string
+	PluginHost                    bool
+	ReadOnlyLibraries             bool
+	Sync                          bool
+	TranscoderActiveVideoSessions int
+	TranscoderAudio               bool
+	TranscoderLyrics              bool
+	TranscoderPhoto               bool
+	TranscoderSubtitles           bool
+	TranscoderVideo               bool
+	Updater                       bool
+	Version                       string
+	VoiceSearch                   bool
+}
+
+// serverCapabilitiesResponse tolerates servers encoding booleans as "1" and "0"
+type serverCapabilitiesResponse struct {
+	MediaContainer struct {
+		AllowCameraUpload             boolish     `json:"allowCameraUpload"`
+		AllowChannelAccess            boolish     `json:"allowChannelAccess"`
+		AllowSharing                  boolish     `json:"allowSharing"`
+		AllowSync                     boolish     `json:"allowSync"`
+		BackgroundProcessing          boolish     `json:"backgroundProcessing"`
+		Certificate                   boolish     `json:"certificate"`
+		CompanionProxy                boolish     `json:"companionProxy"`
+		EventStream                   boolish     `json:"eventStream"`
+		FriendlyName                  string      `json:"friendlyName"`
+		HubSearch                     boolish     `json:"hubSearch"`
+		ItemClusters                  boolish     `json:"itemClusters"`
+		MachineIdentifier             string      `json:"machineIdentifier"`
+		MediaProviders                boolish     `json:"mediaProviders"`
+		Multiuser                     boolish     `json:"multiuser"`
+		MyPlex                        boolish     `json:"myPlex"`
+		MyPlexSubscription            boolish     `json:"myPlexSubscription"`
+		MyPlexUsername                string      `json:"myPlexUsername"`
+		PhotoAutoTag                  boolish     `json:"photoAutoTag"`
+		Platform                      string      `json:"platform"`
+		PlatformVersion               string      `json:"platformVersion"`
+		PluginHost                    boolish     `json:"pluginHost"`
+		ReadOnlyLibraries             boolish     `json:"readOnlyLibraries"`
+		Sync                          boolish     `json:"sync"`
+		TranscoderActiveVideoSessions json.Number `json:"transcoderActiveVideoSessions"`
+		TranscoderAudio               boolish     `json:"transcoderAudio"`
+		TranscoderLyrics              boolish     `json:"transcoderLyrics"`
+		TranscoderPhoto               boolish     `json:"transcoderPhoto"`
+		TranscoderSubtitles           boolish     `json:"transcoderSubtitles"`
+		TranscoderVideo               boolish     `json:"transcoderVideo"`
+		Updater                       boolish     `json:"updater"`
+		Version                       string      `json:"version"`
+		VoiceSearch                   boolish     `json:"voiceSearch"`
+	} `json:"MediaContainer"`
+}
+
 // UserPlexTV plex.tv user. should be used when interacting with plex.tv as the id is an int
 type UserPlexTV struct {
 	XMLName xml.Name `xml:"user"`
@@ -607,6 +1030,352 @@ type User struct {
 	Title        string   `json:"title"`
 }
 
+// DiscoverImage is an image of a DiscoverMetadata (i.e. coverPoster, background, clearLogo)
+type DiscoverImage struct {
+	Alt  string `json:"alt"`
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// DiscoverMetadata is media from plex's online catalog
+type DiscoverMetadata struct {
+	Art                   string          `json:"art"`
+	AudienceRating        FlexibleFloat   `json:"audienceRating"`
+	AudienceRatingImage   string          `json:"audienceRatingImage"`
+	Banner                string          `json:"banner"`
+	ContentRating         string          `json:"contentRating"`
+	Duration              int             `json:"duration"`
+	Genre                 []TaggedData    `json:"Genre"`
+	GUID                  string          `json:"guid"`
+	Image                 []DiscoverImage `json:"Image"`
+	Key                   string          `json:"key"`
+	OriginallyAvailableAt string          `json:"originallyAvailableAt"`
+	Rating                FlexibleFloat   `json:"rating"`
+	RatingKey             string          `json:"ratingKey"`
+	Slug                  string          `json:"slug"`
+	Studio                string          `json:"studio"`
+	Summary               string          `json:"summary"`
+	Tagline               string          `json:"tagline"`
+	Theme                 string          `json:"theme"`
+	Thumb                 string          `json:"thumb"`
+	Title                 string          `json:"title"`
+	Type                  string          `json:"type"`
+	Year                  int             `json:"year"`
+}
+
+// DiscoverMediaContainer contains media from plex's online catalog
+type DiscoverMediaContainer struct {
+	Identifier string             `json:"identifier"`
+	Metadata   []DiscoverMetadata `json:"Metadata"`
+	Offset     int                `json:"offset"`
+	Size       int                `json:"size"`
+	TotalSize  int                `json:"totalSize"`
+}
+
+// DiscoverMetadataResponse is the result of plex's online catalog endpoints
+type DiscoverMetadataResponse struct {
+	MediaContainer DiscoverMediaContainer `json:"MediaContainer"`
+}
+
+type discoverSearchResponse struct {
+	MediaContainer struct {
+		SearchResults []struct {
+			ID           string `json:"id"`
+			Title        string `json:"title"`
+			SearchResult []struct {
+				Score    float64          `json:"score"`
+				Metadata DiscoverMetadata `json:"Metadata"`
+			} `json:"SearchResult"`
+		} `json:"SearchResults"`
+	} `json:"MediaContainer"`
+}
+
+// MetadataMatch is a candidate match of the metadata agent for a piece of media, returned by GetMatches
+type MetadataMatch struct {
+	GUID          string `json:"guid"`
+	LifespanEnded bool   `json:"lifespanEnded"`
+	Matched       bool   `json:"matched"`
+	Name          string `json:"name"`
+	Score         int    `json:"score"`
+	Thumb         string `json:"thumb"`
+	Year          int    `json:"year"`
+}
+
+type metadataMatchesResponse struct {
+	MediaContainer struct {
+		SearchResult []MetadataMatch `json:"SearchResult"`
+	} `json:"MediaContainer"`
+}
+
+// Artwork is a poster or an art (background) available for a piece of media, returned by GetPosters and GetArts
+type Artwork struct {
+	Key string `json:"key"`
+	// Provider is the agent providing the artwork, i.e. com.plexapp.agents.themoviedb, or empty for uploaded artwork
+	Provider string `json:"provider"`
+	// RatingKey identifies the artwork to SetPoster and SetArt
+	RatingKey string `json:"ratingKey"`
+	Selected  bool   `json:"selected"`
+	Thumb     string `json:"thumb"`
+}
+
+type artworkResponse struct {
+	MediaContainer struct {
+		Metadata []Artwork `json:"Metadata"`
+	} `json:"MediaContainer"`
+}
+
+// FilterValue is a possible value of a library filter, i.e. a genre or a year
+type FilterValue struct {
+	FastKey string `json:"fastKey"`
+	// Key is the value to filter by, i.e. genre=Key
+	Key   string `json:"key"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
+
+// LibrarySectionFilter is a field the content of a library section can be filtered by
+type LibrarySectionFilter struct {
+	Filter     string `json:"filter"`
+	FilterType string `json:"filterType"`
+	Key        string `json:"key"`
+	Title      string `json:"title"`
+	Type       string `json:"type"`
+	// Values are the possible values of the filter. Boolean filters (i.e. unwatched) have none.
+	Values []FilterValue `json:"-"`
+}
+
+type libraryFiltersResponse struct {
+	MediaContainer struct {
+		Directory []LibrarySectionFilter `json:"Directory"`
+	} `json:"MediaContainer"`
+}
+
+type filterValuesResponse struct {
+	MediaContainer struct {
+		Directory []FilterValue `json:"Directory"`
+	} `json:"MediaContainer"`
+}
+
+// quality presets of optimized versions, see OptimizeMedia
+const (
+	OptimizeTargetMobile   = 1
+	OptimizeTargetTV       = 2
+	OptimizeTargetOriginal = 3
+)
+
+// OptimizedItem is a job of the server creating optimized versions of media
+type OptimizedItem struct {
+	ID          int                     `json:"id"`
+	Location    []OptimizedItemLocation `json:"Location"`
+	Target      string                  `json:"target"`
+	TargetTagID int                     `json:"targetTagID"`
+	Title       string                  `json:"title"`
+	Type        int                     `json:"type"`
+}
+
+// OptimizedItemLocation is the media an optimize job creates optimized versions of
+type OptimizedItemLocation struct {
+	URI string `json:"uri"`
+}
+
+// optimizes returns whether the job optimizes the media at uri. Servers omitting the location of
+// jobs are matched by title.
+func (i OptimizedItem) optimizes(uri, title string) bool {
+	if len(i.Location) == 0 {
+		return i.Title == title
+	}
+
+	for _, location := range i.Location {
+		if location.URI == uri {
+			return true
+		}
+	}
+
+	return false
+}
+
+type optimizedItemsResponse struct {
+	MediaContainer struct {
+		Item     []OptimizedItem `json:"Item"`
+		Metadata []OptimizedItem `json:"Metadata"`
+	} `json:"MediaContainer"`
+}
+
+// items returns the optimized items regardless of the key the server used for them
+func (r optimizedItemsResponse) items() []OptimizedItem {
+	return append(r.MediaContainer.Item, r.MediaContainer.Metadata...)
+}
+
+// SyncQuality is the quality media is transcoded to when synced to a device
+type SyncQuality struct {
+	// MaxVideoBitrate in kbps
+	MaxVideoBitrate int
+	// VideoQuality is a percentage
+	VideoQuality    int
+	VideoResolution string
+}
+
+// sync qualities of the plex apps
+var (
+	SyncQualityOriginal = SyncQuality{}
+	SyncQuality720p     = SyncQuality{MaxVideoBitrate: 4000, VideoQuality: 100, VideoResolution: "1280x720"}
+	SyncQuality1080p    = SyncQuality{MaxVideoBitrate: 8000, VideoQuality: 60, VideoResolution: "1920x1080"}
+)
+
+// SyncItem is media synced (downloaded) to a device for offline playback
+type SyncItem struct {
+	XMLName           xml.Name `xml:"SyncItem"`
+	ID                int      `xml:"id,attr"`
+	Version           int      `xml:"version,attr"`
+	RootTitle         string   `xml:"rootTitle,attr"`
+	Title             string   `xml:"title,attr"`
+	MetadataType      string   `xml:"metadataType,attr"`
+	ContentType       string   `xml:"contentType,attr"`
+	MachineIdentifier string   `xml:"machineIdentifier,attr"`
+	Status            struct {
+		// State is i.e. pending, processing or complete
+		State                string `xml:"state,attr"`
+		Failure              string `xml:"failure,attr"`
+		FailureCode          string `xml:"failureCode,attr"`
+		ItemsCount           int    `xml:"itemsCount,attr"`
+		ItemsCompleteCount   int    `xml:"itemsCompleteCount,attr"`
+		ItemsDownloadedCount int    `xml:"itemsDownloadedCount,attr"`
+		ItemsReadyCount      int    `xml:"itemsReadyCount,attr"`
+		ItemsSuccessfulCount int    `xml:"itemsSuccessfulCount,attr"`
+	} `xml:"Status"`
+}
+
+// play queue types
+const (
+	PlayQueueTypeVideo = "video"
+	PlayQueueTypeAudio = "audio"
+	PlayQueueTypePhoto = "photo"
+)
+
+// PlayQueue is the ordered list of items a client plays
+type PlayQueue struct {
+	MediaContainer struct {
+		Identifier                      string     `json:"identifier"`
+		Metadata                        []Metadata `json:"Metadata"`
+		PlayQueueID                     int        `json:"playQueueID"`
+		PlayQueueSelectedItemID         int        `json:"playQueueSelectedItemID"`
+		PlayQueueSelectedItemOffset     int        `json:"playQueueSelectedItemOffset"`
+		PlayQueueSelectedMetadataItemID string     `json:"playQueueSelectedMetadataItemID"`
+		PlayQueueShuffled               bool       `json:"playQueueShuffled"`
+		PlayQueueSourceURI              string     `json:"playQueueSourceURI"`
+		PlayQueueTotalCount             int        `json:"playQueueTotalCount"`
+		PlayQueueVersion                int        `json:"playQueueVersion"`
+		Size                            int        `json:"size"`
+	} `json:"MediaContainer"`
+}
+
+// Client is a player connected to the server that can be remote controlled
+type Client struct {
+	Address              string `json:"address"`
+	DeviceClass          string `json:"deviceClass"`
+	Host                 string `json:"host"`
+	MachineIdentifier    string `json:"machineIdentifier"`
+	Name                 string `json:"name"`
+	Port                 int    `json:"port"`
+	Product              string `json:"product"`
+	Protocol             string `json:"protocol"`
+	ProtocolCapabilities string `json:"protocolCapabilities"`
+	ProtocolVersion      string `json:"protocolVersion"`
+	Version              string `json:"version"`
+}
+
+type clientsResponse struct {
+	MediaContainer struct {
+		Server []Client `json:"Server"`
+		Size   int      `json:"size"`
+	} `json:"MediaContainer"`
+}
+
+// aggregation granularities of GetStatistics
+const (
+	StatisticsTimespanMonths  = 1
+	StatisticsTimespanWeeks   = 2
+	StatisticsTimespanDays    = 3
+	StatisticsTimespanHours   = 4
+	StatisticsTimespanSeconds = 6
+)
+
+// StatisticsBandwidth is the bandwidth a device of an account used during a timespan
+type StatisticsBandwidth struct {
+	AccountID int   `json:"accountID"`
+	At        int64 `json:"at"`
+	Bytes     int64 `json:"bytes"`
+	DeviceID  int   `json:"deviceID"`
+	LAN       bool  `json:"lan"`
+	Timespan  int   `json:"timespan"`
+}
+
+// StatisticsResources is the cpu and memory usage of the server during a timespan, in percent
+type StatisticsResources struct {
+	At                       int64   `json:"at"`
+	HostCPUUtilization       float64 `json:"hostCpuUtilization"`
+	HostMemoryUtilization    float64 `json:"hostMemoryUtilization"`
+	ProcessCPUUtilization    float64 `json:"processCpuUtilization"`
+	ProcessMemoryUtilization float64 `json:"processMemoryUtilization"`
+	Timespan                 int     `json:"timespan"`
+}
+
+// Statistics are samples of the server's load over time
+type Statistics struct {
+	Bandwidth []StatisticsBandwidth
+	Resources []StatisticsResources
+}
+
+type statisticsBandwidthResponse struct {
+	MediaContainer struct {
+		StatisticsBandwidth []StatisticsBandwidth `json:"StatisticsBandwidth"`
+	} `json:"MediaContainer"`
+}
+
+type statisticsResourcesResponse struct {
+	MediaContainer struct {
+		StatisticsResources []StatisticsResources `json:"StatisticsResources"`
+	} `json:"MediaContainer"`
+}
+
+// types of a SessionEvent
+const (
+	SessionStarted      = "started"
+	SessionStopped      = "stopped"
+	SessionStateChanged = "stateChanged"
+	SessionError        = "error"
+)
+
+// SessionEvent is a change of the sessions of the server sent by WatchSessions
+type SessionEvent struct {
+	// Type is SessionStarted, SessionStopped, SessionStateChanged or SessionError
+	Type string
+	// Session is the session that changed. It is its last known state when stopped.
+	Session MetadataV1
+	// Err is the error of polling the sessions when Type is SessionError
+	Err error
+}
+
+// HomeUser is a member of your plex home
+type HomeUser struct {
+	ID         int    `xml:"id,attr"`
+	UUID       string `xml:"uuid,attr"`
+	Title      string `xml:"title,attr"`
+	Username   string `xml:"username,attr"`
+	Email      string `xml:"email,attr"`
+	Thumb      string `xml:"thumb,attr"`
+	Admin      bool   `xml:"admin,attr"`
+	Guest      bool   `xml:"guest,attr"`
+	Restricted bool   `xml:"restricted,attr"`
+	Protected  bool   `xml:"protected,attr"`
+}
+
+type homeUsersResponse struct {
+	XMLName xml.Name   `xml:"MediaContainer"`
+	Size    int        `xml:"size,attr"`
+	User    []HomeUser `xml:"User"`
+}
+
 // SignInResponse response from plex.tv sign in
 type SignInResponse UserPlexTV
 
@@ -696,8 +1465,10 @@ type headers struct {
 	Product                string
 	Version                string
 	Device                 string
+	Language               string
 	ContainerSize          string
 	ContainerStart         string
+	Range                  string
 	Token                  string
 	Accept                 string
 	ContentType            string
@@ -711,28 +1482,68 @@ type request struct {
 
 // Sessions
 
-// TranscodeSessionsResponse is the result for transcode session endpoint /transcode/sessions
+// TranscodeSessionsResponse is the result for transcode session endpoint /transcode/sessions.
+// Use Sessions rather than Children or MediaContainer, which depend on the version of the server
 type TranscodeSessionsResponse struct {
-	Children []struct {
-		ElementType   string  `json:"_elementType"`
-		AudioChannels int     `json:"audioChannels"`
-		AudioCodec    string  `json:"audioCodec"`
-		AudioDecision string  `json:"audioDecision"`
-		Container     string  `json:"container"`
-		Context       string  `json:"context"`
-		Duration      int     `json:"duration"`
-		Height        int     `json:"height"`
-		Key           string  `json:"key"`
-		Progress      float64 `json:"progress"`
-		Protocol      string  `json:"protocol"`
-		Remaining     int     `json:"remaining"`
-		Speed         float64 `json:"speed"`
-		Throttled     bool    `json:"throttled"`
-		VideoCodec    string  `json:"videoCodec"`
-		VideoDecision string  `json:"videoDecision"`
-		Width         int     `json:"width"`
-	} `json:"_children"`
-	ElementType string `json:"_elementType"`
+	Children       []LegacyTranscodeSession `json:"_children"`
+	ElementType    string                   `json:"_elementType"`
+	MediaContainer struct {
+		TranscodeSession []TranscodeSession `json:"TranscodeSession"`
+	} `json:"MediaContainer"`
+}
+
+// LegacyTranscodeSession is a transcode session in the _children of older servers
+type LegacyTranscodeSession struct {
+	ElementType   string  `json:"_elementType"`
+	AudioChannels int     `json:"audioChannels"`
+	AudioCodec    string  `json:"audioCodec"`
+	AudioDecision string  `json:"audioDecision"`
+	Container     string  `json:"container"`
+	Context       string  `json:"context"`
+	Duration      int     `json:"duration"`
+	Height        int     `json:"height"`
+	Key           string  `json:"key"`
+	Progress      float64 `json:"progress"`
+	Protocol      string  `json:"protocol"`
+	Remaining     int     `json:"remaining"`
+	Speed         float64 `json:"speed"`
+	Throttled     bool    `json:"throttled"`
+	VideoCodec    string  `json:"videoCodec"`
+	VideoDecision string  `json:"videoDecision"`
+	Width         int     `json:"width"`
+}
+
+// TranscodeSession converts a legacy transcode session
+func (l LegacyTranscodeSession) TranscodeSession() TranscodeSession {
+	return TranscodeSession{
+		AudioChannels: int64(l.AudioChannels),
+		AudioCodec:    l.AudioCodec,
+		AudioDecision: l.AudioDecision,
+		Container:     l.Container,
+		Context:       l.Context,
+		Duration:      int64(l.Duration),
+		Key:           l.Key,
+		Progress:      l.Progress,
+		Protocol:      l.Protocol,
+		Remaining:     int64(l.Remaining),
+		Speed:         l.Speed,
+		Throttled:     l.Throttled,
+		VideoCodec:    l.VideoCodec,
+		VideoDecision: l.VideoDecision,
+	}
+}
+
+// Sessions returns the transcode sessions whichever shape the server replied with
+func (r TranscodeSessionsResponse) Sessions() []TranscodeSession {
+	sessions := make([]TranscodeSession, 0, len(r.MediaContainer.TranscodeSession)+len(r.Children))
+
+	sessions = append(sessions, r.MediaContainer.TranscodeSession...)
+
+	for _, child := range r.Children {
+		sessions = append(sessions, child.TranscodeSession())
+	}
+
+	return sessions
 }
 
 // Rating ...
@@ -743,6 +1554,7 @@ type Rating struct {
 	Value FixedRating `json:"value"`
 }
 
+// FixedRating is a rating plex encodes either as a number or as a string
 type FixedRating float32
 
 func (value *FixedRating) UnmarshalJSON(data []byte) error {
@@ -764,71 +1576,161 @@ func (value *FixedRating) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// FlexibleFloat is a number plex encodes either as a number or as a string, depending on the
+// endpoint and the server version. Empty and invalid strings decode as 0.
+type FlexibleFloat float64
+
+// UnmarshalJSON decodes a number, a numeric string, an empty string or null
+func (value *FlexibleFloat) UnmarshalJSON(data []byte) error {
+	number, err := flexibleNumber(data)
+
+	if err != nil {
+		return err
+	}
+
+	conv, _ := strconv.ParseFloat(number, 64)
+	*value = FlexibleFloat(conv)
+	return nil
+}
+
+// FlexibleInt is an integer plex encodes either as a number or as a string, depending on the
+// endpoint and the server version. Decimals are truncated, empty and invalid strings decode as 0.
+type FlexibleInt int64
+
+// UnmarshalJSON decodes a number, a numeric string, an empty string or null
+func (value *FlexibleInt) UnmarshalJSON(data []byte) error {
+	number, err := flexibleNumber(data)
+
+	if err != nil {
+		return err
+	}
+
+	if conv, err := strconv.ParseInt(number, 10, 64); err == nil {
+		*value = FlexibleInt(conv)
+		return nil
+	}
+
+	conv, _ := strconv.ParseFloat(number, 64)
+	*value = FlexibleInt(conv)
+	return nil
+}
+
+// flexibleNumber returns the number encoded in data, which is either a number or a string
+func flexibleNumber(data []byte) (string, error) {
+	var number json.Number
+
+	if err := json.Unmarshal(data, &number); err == nil {
+		return number.String(), nil
+	}
+
+	var isString string
+
+	if err := json.Unmarshal(data, &isString); err != nil {
+		return "", err
+	}
+
+	return isString, nil
+}
+
 // Stream ...
 type Stream struct {
-	AlbumGain          string  `json:"albumGain"`
-	AlbumPeak          string  `json:"albumPeak"`
-	AlbumRange         string  `json:"albumRange"`
-	Anamorphic         bool    `json:"anamorphic"`
-	AudioChannelLayout string  `json:"audioChannelLayout"`
-	BitDepth           int     `json:"bitDepth"`
-	Bitrate            int     `json:"bitrate"`
-	BitrateMode        string  `json:"bitrateMode"`
-	Cabac              string  `json:"cabac"`
-	Channels           int     `json:"channels"`
-	ChromaLocation     string  `json:"chromaLocation"`
-	ChromaSubsampling  string  `json:"chromaSubsampling"`
-	Codec              string  `json:"codec"`
-	CodecID            string  `json:"codecID"`
-	ColorRange         string  `json:"colorRange"`
-	ColorSpace         string  `json:"colorSpace"`
-	Default            bool    `json:"default"`
-	DisplayTitle       string  `json:"displayTitle"`
-	Duration           float64 `json:"duration"`
-	FrameRate          float64 `json:"frameRate"`
-	FrameRateMode      string  `json:"frameRateMode"`
-	Gain               string  `json:"gain"`
-	HasScalingMatrix   bool    `json:"hasScalingMatrix"`
-	Height             int     `json:"height"`
-	ID                 int     `json:"id"`
-	Index              int     `json:"index"`
-	Language           string  `json:"language"`
-	LanguageCode       string  `json:"languageCode"`
-	Level              int     `json:"level"`
-	Location           string  `json:"location"`
-	Loudness           string  `json:"loudness"`
-	Lra                string  `json:"lra"`
-	Peak               string  `json:"peak"`
-	PixelAspectRatio   string  `json:"pixelAspectRatio"`
-	PixelFormat        string  `json:"pixelFormat"`
-	Profile            string  `json:"profile"`
-	RefFrames          int     `json:"refFrames"`
-	SamplingRate       int     `json:"samplingRate"`
-	ScanType           string  `json:"scanType"`
-	Selected           bool    `json:"selected"`
-	StreamIdentifier   string  `json:"streamIdentifier"`
-	StreamType         int     `json:"streamType"`
-	Width              int     `json:"width"`
-}
-
-// StreamV1 stream info version 1
+	AlbumGain            string  `json:"albumGain"`
+	AlbumPeak            string  `json:"albumPeak"`
+	AlbumRange           string  `json:"albumRange"`
+	Anamorphic           bool    `json:"anamorphic"`
+	AudioChannelLayout   string  `json:"audioChannelLayout"`
+	BitDepth             int     `json:"bitDepth"`
+	Bitrate              int     `json:"bitrate"`
+	BitrateMode          string  `json:"bitrateMode"`
+	Cabac                string  `json:"cabac"`
+	Channels             int     `json:"channels"`
+	ChromaLocation       string  `json:"chromaLocation"`
+	ChromaSubsampling    string  `json:"chromaSubsampling"`
+	Codec                string  `json:"codec"`
+	CodecID              string  `json:"codecID"`
+	ColorRange           string  `json:"colorRange"`
+	ColorSpace           string  `json:"colorSpace"`
+	Default              bool    `json:"default"`
+	DisplayTitle         string  `json:"displayTitle"`
+	Duration             float64 `json:"duration"`
+	ExtendedDisplayTitle string  `json:"extendedDisplayTitle"`
+	FrameRate            float64 `json:"frameRate"`
+	FrameRateMode        string  `json:"frameRateMode"`
+	Gain                 string  `json:"gain"`
+	HasScalingMatrix     bool    `json:"hasScalingMatrix"`
+	Height               int     `json:"height"`
+	ID                   int     `json:"id"`
+	Index                int     `json:"index"`
+	Language             string  `json:"language"`
+	LanguageCode         string  `json:"languageCode"`
+	Level                int     `json:"level"`
+	Location             string  `json:"location"`
+	Loudness             string  `json:"loudness"`
+	Lra                  string  `json:"lra"`
+	Peak                 string  `json:"peak"`
+	PixelAspectRatio     string  `json:"pixelAspectRatio"`
+	PixelFormat          string  `json:"pixelFormat"`
+	Profile              string  `json:"profile"`
+	RefFrames            int     `json:"refFrames"`
+	SamplingRate         int     `json:"samplingRate"`
+	ScanType             string  `json:"scanType"`
+	Selected             bool    `json:"selected"`
+	StreamIdentifier     string  `json:"streamIdentifier"`
+	StreamType           int     `json:"streamType"`
+	Title                string  `json:"title"`
+	Width                int     `json:"width"`
+}
+
+// MediaType is the type of a piece of media as plex numbers it when filtering library content
+type MediaType int
+
+// media types, see GetMediaTypeID for their names
+const (
+	MediaTypeMovie        MediaType = 1
+	MediaTypeShow         MediaType = 2
+	MediaTypeSeason       MediaType = 3
+	MediaTypeEpisode      MediaType = 4
+	MediaTypeTrailer      MediaType = 5
+	MediaTypeComic        MediaType = 6
+	MediaTypePerson       MediaType = 7
+	MediaTypeArtist       MediaType = 8
+	MediaTypeAlbum        MediaType = 9
+	MediaTypeTrack        MediaType = 10
+	MediaTypePhotoAlbum   MediaType = 11
+	MediaTypePicture      MediaType = 12
+	MediaTypePhoto        MediaType = 13
+	MediaTypeClip         MediaType = 14
+	MediaTypePlaylistItem MediaType = 15
+)
+
+// StreamType is the type of a Stream
+type StreamType int
+
+// stream types of a Stream
+const (
+	StreamTypeVideo    StreamType = 1
+	StreamTypeAudio    StreamType = 2
+	StreamTypeSubtitle StreamType = 3
+)
+
+// StreamV1 stream info version 1, part of PartV1. Use Normalize to convert it to a Stream.
 type StreamV1 struct {
 	Stream
-	BitDepth         int     `json:"bitDepth"`
-	Default          bool    `json:"default"`
-	Bitrate          int     `json:"bitrate"`
-	FrameRate        float64 `json:"frameRate"`
-	HasScalingMatrix bool    `json:"hasScalingMatrix"`
-	Height           int     `json:"height"`
-	Width            int     `json:"width"`
-	ID               int     `json:"id,string"`
-	Index            int     `json:"index"`
-	Level            int     `json:"level"`
-	RefFrames        int     `json:"refFrames"`
-	StreamType       int     `json:"streamType"`
-	Channels         int     `json:"channels"`
-	SamplingRate     int     `json:"samplingRate"`
-	Selected         bool    `json:"selected"`
+	BitDepth         int         `json:"bitDepth"`
+	Default          bool        `json:"default"`
+	Bitrate          int         `json:"bitrate"`
+	FrameRate        float64     `json:"frameRate"`
+	HasScalingMatrix bool        `json:"hasScalingMatrix"`
+	Height           int         `json:"height"`
+	Width            int         `json:"width"`
+	ID               FlexibleInt `json:"id"`
+	Index            int         `json:"index"`
+	Level            int         `json:"level"`
+	RefFrames        int         `json:"refFrames"`
+	StreamType       int         `json:"streamType"`
+	Channels         int         `json:"channels"`
+	SamplingRate     int         `json:"samplingRate"`
+	Selected         bool        `json:"selected"`
 }
 
 // Part ...
@@ -849,15 +1751,15 @@ type Part struct {
 	VideoProfile          string   `json:"videoProfile"`
 }
 
-// PartV1 part version 1
+// PartV1 part version 1, part of MediaV1. Use Normalize to convert it to a Part.
 type PartV1 struct {
 	Part
-	Duration              int64      `json:"duration"`
-	Has64bitOffsets       bool       `json:"has64bitOffsets"`
-	ID                    int        `json:"id,string"`
-	OptimizedForStreaming bool       `json:"optimizedForStreaming"`
-	Size                  int        `json:"size"`
-	Stream                []StreamV1 `json:"Stream"`
+	Duration              int64       `json:"duration"`
+	Has64bitOffsets       bool        `json:"has64bitOffsets"`
+	ID                    FlexibleInt `json:"id"`
+	OptimizedForStreaming bool        `json:"optimizedForStreaming"`
+	Size                  int         `json:"size"`
+	Stream                []StreamV1  `json:"Stream"`
 }
 
 // Player ...
@@ -0,0 +1,185 @@
+package plex
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// NotificationClient listens to the notifications of a server until it is closed.
+// It is an alternative to SubscribeToNotifications.
+type NotificationClient struct {
+	plex   *Plex
+	events *NotificationEvents
+
+	// CloseTimeout is how long Close waits for the server to acknowledge the close handshake
+	CloseTimeout time.Duration
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	closing chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	err     error
+}
+
+// NewNotificationClient creates a client dispatching the notifications of the server to events
+func (p *Plex) NewNotificationClient(events *NotificationEvents) *NotificationClient {
+	return &NotificationClient{
+		plex:         p,
+		events:       events,
+		CloseTimeout: time.Second,
+	}
+}
+
+// Start connects to the server and dispatches notifications in the background
+func (n *NotificationClient) Start() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn != nil {
+		return errors.New("notification client already started")
+	}
+
+	c, err := n.plex.dialNotifications()
+
+	if err != nil {
+		return err
+	}
+
+	n.conn = c
+	n.closing = make(chan struct{})
+	n.done = make(chan struct{})
+	n.stopped = make(chan struct{})
+
+	go func() {
+		// the connection is closed once the server closed it, after done so keepAlive does not
+		// report writing to the closed connection as an error
+		defer c.Close()
+		defer close(n.done)
+
+		if err := n.plex.readNotifications(c, n.events); err != nil {
+			n.setErr(err)
+		}
+	}()
+
+	go n.keepAlive(c)
+
+	return nil
+}
+
+// keepAlive pings the server every second and is the only writer of c until Close is called
+func (n *NotificationClient) keepAlive(c *websocket.Conn) {
+	defer close(n.stopped)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case t := <-ticker.C:
+			if err := c.WriteMessage(websocket.TextMessage, []byte(t.String())); err != nil {
+				// the server closing the connection is reported by the reader
+				select {
+				case <-n.done:
+				default:
+					if !errors.Is(err, websocket.ErrCloseSent) {
+						n.setErr(err)
+					}
+				}
+
+				return
+			}
+		case <-n.closing:
+			return
+		case <-n.done:
+			return
+		}
+	}
+}
+
+// Done is closed when the connection to the server is closed. It is nil until Start succeeds.
+func (n *NotificationClient) Done() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.done
+}
+
+// Err returns the error that closed the connection, if any
+func (n *NotificationClient) Err() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.err
+}
+
+// Close performs the close handshake with the server and blocks until the connection is
+// closed or CloseTimeout expires. It returns any error of the handshake or of the connection.
+func (n *NotificationClient) Close() error {
+	n.mu.Lock()
+	c := n.conn
+
+	if c == nil {
+		n.mu.Unlock()
+		return errors.New("notification client not started")
+	}
+
+	select {
+	case <-n.closing:
+		n.mu.Unlock()
+		return errors.New("notification client already closed")
+	default:
+		close(n.closing)
+	}
+
+	n.mu.Unlock()
+
+	<-n.stopped
+
+	var err error
+
+	select {
+	case <-n.done:
+		// the server already closed the connection
+	default:
+		err = c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+
+		// the server started the close handshake, which was answered while reading
+		if errors.Is(err, websocket.ErrCloseSent) {
+			err = nil
+		}
+
+		if err == nil {
+			select {
+			case <-n.done:
+			case <-time.After(n.CloseTimeout):
+				err = errors.New("timed out waiting for the server to close the connection")
+			}
+		}
+
+		// unblock the reader, which closes the connection when it returns
+		if err != nil {
+			c.Close()
+		}
+	}
+
+	<-n.done
+
+	if err != nil {
+		return err
+	}
+
+	return n.Err()
+}
+
+func (n *NotificationClient) setErr(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.err == nil {
+		n.err = err
+	}
+}
@@ -0,0 +1,194 @@
+package plex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newNotificationServer upgrades every request to a websocket handled by handler
+func newNotificationServer(handler func(c *websocket.Conn)) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+
+		if err != nil {
+			return
+		}
+
+		defer c.Close()
+
+		handler(c)
+	}))
+}
+
+// answerClose reads until the connection closes, answering the close handshake of the client
+func answerClose(c *websocket.Conn) {
+	for {
+		if _, _, err := c.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func TestNotificationClientStart(t *testing.T) {
+	server := newNotificationServer(func(c *websocket.Conn) {
+		c.WriteMessage(websocket.TextMessage, []byte(`{"NotificationContainer":{"type":"playing","size":1,"PlaySessionStateNotification":[{"sessionKey":"1","state":"playing"}]}}`))
+
+		answerClose(c)
+	})
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123", WithInsecureSkipVerify())
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	playing := make(chan NotificationContainer, 1)
+
+	events := NewNotificationEvents()
+	events.OnPlaying(func(n NotificationContainer) {
+		playing <- n
+	})
+
+	client := p.NewNotificationClient(events)
+
+	if err := client.Start(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if err := client.Start(); err == nil {
+		t.Error("Expected: an error starting the client twice\n Got: nil")
+	}
+
+	select {
+	case n := <-playing:
+		if len(n.PlaySessionStateNotification) != 1 || n.PlaySessionStateNotification[0].SessionKey != "1" {
+			t.Errorf("Expected: the playing notification of session 1\n Got: %+v", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected: a playing notification\n Got: none")
+	}
+
+	if err := client.Close(); err != nil {
+		t.Errorf("Expected: a clean close\n Got: %v", err)
+	}
+}
+
+func TestNotificationClientServerClose(t *testing.T) {
+	server := newNotificationServer(func(c *websocket.Conn) {
+		c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+
+		answerClose(c)
+	})
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123", WithInsecureSkipVerify())
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	client := p.NewNotificationClient(NewNotificationEvents())
+
+	if err := client.Start(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	select {
+	case <-client.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("Expected: the connection closed by the server\n Got: still open")
+		return
+	}
+
+	if err := client.Err(); err != nil {
+		t.Errorf("Expected: no error after a normal close\n Got: %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Errorf("Expected: a clean close after the server closed the connection\n Got: %v", err)
+	}
+}
+
+func TestNotificationClientCloseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	// never read, so the close handshake of the client is never answered
+	server := newNotificationServer(func(c *websocket.Conn) {
+		<-release
+	})
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123", WithInsecureSkipVerify())
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	client := p.NewNotificationClient(NewNotificationEvents())
+	client.CloseTimeout = 50 * time.Millisecond
+
+	if err := client.Start(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	start := time.Now()
+
+	if err := client.Close(); err == nil {
+		t.Error("Expected: a timeout error\n Got: nil")
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected: Close to return after CloseTimeout\n Got: %v", elapsed)
+	}
+
+	select {
+	case <-client.Done():
+	default:
+		t.Error("Expected: the connection closed after the timeout\n Got: still open")
+	}
+}
+
+func TestNotificationClientDoubleClose(t *testing.T) {
+	server := newNotificationServer(answerClose)
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123", WithInsecureSkipVerify())
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	client := p.NewNotificationClient(NewNotificationEvents())
+
+	if err := client.Close(); err == nil {
+		t.Error("Expected: an error closing a client that was not started\n Got: nil")
+	}
+
+	if err := client.Start(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if err := client.Close(); err != nil {
+		t.Errorf("Expected: a clean close\n Got: %v", err)
+	}
+
+	if err := client.Close(); err == nil {
+		t.Error("Expected: an error closing the client twice\n Got: nil")
+	}
+}
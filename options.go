@@ -0,0 +1,220 @@
+package plex
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Option configures a Plex created with New or SignIn, or the requests of RequestPIN and CheckPIN
+type Option func(*Plex)
+
+// WithTimeout sets the timeout of api requests. Downloads are not affected.
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Plex) {
+		p.HTTPClient.Timeout = timeout
+	}
+}
+
+// WithTransport sets the transport used for api requests and downloads, i.e. to
+// configure proxies or tls
+func WithTransport(transport http.RoundTripper) Option {
+	return func(p *Plex) {
+		p.HTTPClient.Transport = transport
+		p.DownloadClient.Transport = transport
+	}
+}
+
+// WithHTTPClient uses the configuration of client (transport, timeout, cookie jar and redirect policy)
+// for api requests. The client's transport, and thus its connection pool, is shared.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Plex) {
+		if client == nil {
+			return
+		}
+
+		p.HTTPClient = *client
+	}
+}
+
+// WithLogger sets the logger receiving the client's internal log messages
+func WithLogger(logger Logger) Option {
+	return func(p *Plex) {
+		p.Logger = logger
+	}
+}
+
+// WithPlexTVURL sends plex.tv requests (sign in, pins, friends, devices, resources, etc) to baseURL instead of https://plex.tv.
+// DiscoverSearch and GetDiscoverMetadata, which use other plex.tv hosts, are sent to baseURL as well.
+func WithPlexTVURL(baseURL string) Option {
+	return func(p *Plex) {
+		p.PlexTVURL = baseURL
+	}
+}
+
+// WithLanguage requests localized content in lang (i.e. de or de-DE) via the X-Plex-Language and
+// Accept-Language headers. It is honored by plex's online services, such as DiscoverSearch and
+// GetDiscoverMetadata, and by plex.tv. Your server returns metadata in the language of the library
+// (see CreateLibraryParams) and only localizes some strings such as hub titles.
+func WithLanguage(lang string) Option {
+	return func(p *Plex) {
+		p.Headers.Language = lang
+	}
+}
+
+// ClientInfo describes the application making requests. Plex shows it in its dashboard and
+// logs, and playback control requires a stable ClientIdentifier
+type ClientInfo struct {
+	Product          string
+	Version          string
+	Platform         string
+	PlatformVersion  string
+	Device           string
+	ClientIdentifier string
+}
+
+// WithClientInfo sets the X-Plex-* headers describing your application. Empty fields keep their defaults.
+func WithClientInfo(info ClientInfo) Option {
+	return func(p *Plex) {
+		p.SetClientInfo(info)
+	}
+}
+
+// SetClientInfo sets the X-Plex-* headers describing your application, i.e. on a Plex created
+// with SignIn. Empty fields are left unchanged.
+func (p *Plex) SetClientInfo(info ClientInfo) {
+	if info.Product != "" {
+		p.Headers.Product = info.Product
+	}
+
+	if info.Version != "" {
+		p.Headers.Version = info.Version
+	}
+
+	if info.Platform != "" {
+		p.Headers.Platform = info.Platform
+	}
+
+	if info.PlatformVersion != "" {
+		p.Headers.PlatformVersion = info.PlatformVersion
+	}
+
+	if info.Device != "" {
+		p.Headers.Device = info.Device
+	}
+
+	if info.ClientIdentifier != "" {
+		p.ClientIdentifier = info.ClientIdentifier
+		p.Headers.ClientIdentifier = info.ClientIdentifier
+	}
+}
+
+// WithResponseHook calls hook with every response of the api before it is decoded. The hook may
+// read the body, i.e. to log it.
+func WithResponseHook(hook func(resp *http.Response)) Option {
+	return func(p *Plex) {
+		p.ResponseHook = hook
+	}
+}
+
+// WithRequestContext cancels requests when ctx is done, i.e. to cancel RequestPIN or CheckPIN.
+// Use WithContext to cancel the requests of an existing Plex
+func WithRequestContext(ctx context.Context) Option {
+	return func(p *Plex) {
+		p.ctx = ctx
+	}
+}
+
+// RetryPolicy configures how GET requests are retried after connection errors and 5xx responses.
+// Other requests, and 4xx responses, are never retried.
+type RetryPolicy struct {
+	// Count is the number of retries after the first attempt
+	Count int
+	// BaseDelay is the delay before the first retry, doubling on every following retry
+	BaseDelay time.Duration
+	// Jitter is the maximum random duration added to every delay
+	Jitter time.Duration
+}
+
+func (r RetryPolicy) delay(attempt int) time.Duration {
+	delay := r.BaseDelay << uint(attempt)
+
+	if r.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(r.Jitter)))
+	}
+
+	return delay
+}
+
+// WithRetry retries GET requests up to count times, waiting baseDelay (doubled on each retry)
+// plus up to jitter between attempts. Use WithContext to stop retrying a request
+func WithRetry(count int, baseDelay, jitter time.Duration) Option {
+	return func(p *Plex) {
+		p.Retry = RetryPolicy{Count: count, BaseDelay: baseDelay, Jitter: jitter}
+	}
+}
+
+// WithRateLimit limits requests to rps per second, allowing bursts of up to burst requests.
+// Requests block until they may be sent, or until the context of WithContext is done. The limit is
+// shared by every copy of the Plex and by every Plex the returned Option is passed to, so reuse it
+// to limit several clients together, i.e. New and CheckPIN.
+func WithRateLimit(rps float64, burst int) Option {
+	var limiter *rateLimiter
+
+	if rps > 0 {
+		limiter = newRateLimiter(rps, burst)
+	}
+
+	return func(p *Plex) {
+		p.limiter = limiter
+	}
+}
+
+// WithInsecureSkipVerify disables verification of the server's tls certificate for api requests,
+// downloads and notifications. This is sometimes needed to connect to a local server by its ip address
+// as it presents a *.plex.direct certificate, but it also allows anyone on the network path to
+// impersonate the server and read your token. Prefer connecting through the plex.direct address or WithRootCAs.
+func WithInsecureSkipVerify() Option {
+	return func(p *Plex) {
+		p.configureTLS(func(config *tls.Config) {
+			config.InsecureSkipVerify = true
+		})
+	}
+}
+
+// WithRootCAs verifies the server's tls certificate against pool instead of the system's certificates
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(p *Plex) {
+		p.configureTLS(func(config *tls.Config) {
+			config.RootCAs = pool
+		})
+	}
+}
+
+// configureTLS applies configure to the tls config of both clients' transports. Transports other
+// than *http.Transport are left untouched.
+func (p *Plex) configureTLS(configure func(config *tls.Config)) {
+	for _, client := range []*http.Client{&p.HTTPClient, &p.DownloadClient} {
+		var transport *http.Transport
+
+		switch t := client.Transport.(type) {
+		case nil:
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			transport = t.Clone()
+		default:
+			continue
+		}
+
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+
+		configure(transport.TLSClientConfig)
+
+		client.Transport = transport
+	}
+}
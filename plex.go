@@ -3,6 +3,7 @@ package plex
 // plex is a Plex Media Server and Plex.tv client
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -15,13 +16,18 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-const plexURL = "https://plex.tv"
+// DefaultPlexTVURL is the base url of plex.tv used without WithPlexTVURL
+const DefaultPlexTVURL = "https://plex.tv"
 
 func defaultHeaders() headers {
 	version := "0.0.1"
@@ -33,16 +39,61 @@ func defaultHeaders() headers {
 		Version:          version,
 		Device:           runtime.GOOS + " " + runtime.GOARCH,
 		ClientIdentifier: "go-plex-client-v" + version,
-		ContainerSize:    "Plex-Container-Size=50",
-		ContainerStart:   "X-Plex-Container-Start=0",
 		Accept:           "application/json",
 		ContentType:      "application/json",
 	}
 }
 
+// GenerateClientIdentifier returns a new random client identifier. Generate it once, persist it
+// and pass it to every session via WithClientInfo: plex ties devices, PINs and playback control
+// to the identifier, and they behave erratically when it changes
+func GenerateClientIdentifier() (string, error) {
+	id, err := uuid.NewRandom()
+
+	if err != nil {
+		return "", err
+	}
+
+	return id.String(), nil
+}
+
+// WithContext returns a copy of p whose requests are cancelled when ctx is done, including the waits
+// for the rate limiter and between retries. The copy shares the rate limit of p.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//
+//	sessions, err := p.WithContext(ctx).GetSessions()
+func (p *Plex) WithContext(ctx context.Context) *Plex {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	p2 := *p
+	p2.ctx = ctx
+
+	return &p2
+}
+
+// plexTVURL returns the base url of plex.tv requests
+func (p Plex) plexTVURL() string {
+	if p.PlexTVURL != "" {
+		return strings.TrimSuffix(p.PlexTVURL, "/")
+	}
+
+	return DefaultPlexTVURL
+}
+
 // New creates a new plex instance that is required to
-// to make requests to your Plex Media Server
-func New(baseURL, token string) (*Plex, error) {
+// to make requests to your Plex Media Server. Options such as WithTimeout
+// customize how requests are made.
+//
+// A new client identifier is generated unless WithClientInfo sets one. Persist your identifier
+// and pass it to every session, see GenerateClientIdentifier.
+//
+// A Plex is safe for concurrent use by multiple goroutines as long as its fields
+// are not modified while requests are in flight.
+func New(baseURL, token string, opts ...Option) (*Plex, error) {
 	var p Plex
 
 	// allow empty url so caller can use GetServers() to set the server url later
@@ -58,14 +109,22 @@ func New(baseURL, token string) (*Plex, error) {
 	p.DownloadClient = http.Client{}
 
 	p.Headers = defaultHeaders()
-	// id, err := uuid.NewRandom()
+	p.serverNames = &serverNameCache{}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	if p.ClientIdentifier == "" {
+		id, err := GenerateClientIdentifier()
 
-	// if err != nil {
-	// 	return &p, err
-	// }
+		if err != nil {
+			return &p, err
+		}
+
+		p.ClientIdentifier = id
+	}
 
-	// p.ClientIdentifier = id.String()
-	p.ClientIdentifier = p.Headers.ClientIdentifier
 	p.Headers.ClientIdentifier = p.ClientIdentifier
 
 	// has url and token
@@ -92,22 +151,31 @@ func New(baseURL, token string) (*Plex, error) {
 }
 
 // SignIn creates a plex instance using a user name and password instead of an auth
-// token.
-func SignIn(username, password string) (*Plex, error) {
-	id, err := uuid.NewRandom()
+// token. opts configure the sign in request and the returned Plex, i.e. WithPlexTVURL
+// to sign in against a mock server.
+func SignIn(username, password string, opts ...Option) (*Plex, error) {
+	id, err := GenerateClientIdentifier()
 
 	if err != nil {
 		return &Plex{}, err
 	}
 
 	p := Plex{
-		ClientIdentifier: id.String(),
+		ClientIdentifier: id,
 		HTTPClient: http.Client{
 			Timeout: 3 * time.Second,
 		},
+		Headers:     defaultHeaders(),
+		serverNames: &serverNameCache{},
 	}
 
-	query := plexURL + "/api/v2/users/signin"
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	p.Headers.ClientIdentifier = p.ClientIdentifier
+
+	query := p.plexTVURL() + "/api/v2/users/signin"
 
 	// Encode login in the specific format they require
 	body := url.Values{}
@@ -174,8 +242,9 @@ func (p *Plex) Search(title string) (SearchResults, error) {
 	return results, nil
 }
 
-// GetMetadata can get some media info
-func (p *Plex) GetMetadata(key string) (MediaMetadata, error) {
+// GetMetadata can get some media info. include adds optional data, i.e. IncludeMarkers for the intro
+// and credits markers of an episode
+func (p *Plex) GetMetadata(key string, include ...MetadataInclude) (MediaMetadata, error) {
 	if key == "" {
 		return MediaMetadata{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
 	}
@@ -184,6 +253,16 @@ func (p *Plex) GetMetadata(key string) (MediaMetadata, error) {
 
 	query := fmt.Sprintf("%s/library/metadata/%s", p.URL, key)
 
+	if len(include) > 0 {
+		params := url.Values{}
+
+		for _, i := range include {
+			params.Set(string(i), "1")
+		}
+
+		query += "?" + params.Encode()
+	}
+
 	newHeaders := p.Headers
 
 	resp, err := p.get(query, newHeaders)
@@ -205,6 +284,106 @@ func (p *Plex) GetMetadata(key string) (MediaMetadata, error) {
 	return results, nil
 }
 
+// maxMetadataKeysLength caps the length of the comma separated rating keys of a single request to stay
+// well below the url length limits of servers and proxies
+const maxMetadataKeysLength = 2000
+
+// GetMetadataMulti gets the metadata of many items at once. Large lists are fetched in several
+// requests whose results are merged in order.
+func (p *Plex) GetMetadataMulti(ratingKeys []string) (MediaMetadata, error) {
+	var results MediaMetadata
+
+	if len(ratingKeys) == 0 {
+		return results, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	var chunk []string
+	var chunkLength int
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		var result MediaMetadata
+
+		query := fmt.Sprintf("%s/library/metadata/%s", p.URL, strings.Join(chunk, ","))
+
+		if err := p.getJSON(query, &result); err != nil {
+			return err
+		}
+
+		if len(results.MediaContainer.Metadata) == 0 {
+			results = result
+		} else {
+			results.MediaContainer.Metadata = append(results.MediaContainer.Metadata, result.MediaContainer.Metadata...)
+		}
+
+		chunk = nil
+		chunkLength = 0
+
+		return nil
+	}
+
+	for _, key := range ratingKeys {
+		if key == "" {
+			return MediaMetadata{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+		}
+
+		if chunkLength+len(key)+1 > maxMetadataKeysLength {
+			if err := flush(); err != nil {
+				return MediaMetadata{}, err
+			}
+		}
+
+		chunk = append(chunk, url.PathEscape(key))
+		chunkLength += len(key) + 1
+	}
+
+	if err := flush(); err != nil {
+		return MediaMetadata{}, err
+	}
+
+	results.MediaContainer.Size = len(results.MediaContainer.Metadata)
+
+	return results, nil
+}
+
+// GetRelated returns hubs of media related to a piece of media, i.e. "More Like This" or "More Directed by ...".
+// Each hub's Title is its label and Metadata its items.
+func (p *Plex) GetRelated(ratingKey string) (Hubs, error) {
+	if ratingKey == "" {
+		return Hubs{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	var results Hubs
+
+	if err := p.getJSON(fmt.Sprintf("%s/library/metadata/%s/related", p.URL, ratingKey), &results); err != nil {
+		return Hubs{}, err
+	}
+
+	return results, nil
+}
+
+// GetHubs returns the hubs of the home screen of a library section, i.e. "Recently Added" or
+// "Top Rated". An empty sectionKey returns the hubs of the home screen across libraries.
+// Hubs with More set have more items than they contain, which can be fetched with their Key.
+func (p *Plex) GetHubs(sectionKey string) (Hubs, error) {
+	query := fmt.Sprintf("%s/hubs", p.URL)
+
+	if sectionKey != "" {
+		query = fmt.Sprintf("%s/hubs/sections/%s", p.URL, sectionKey)
+	}
+
+	var results Hubs
+
+	if err := p.getJSON(query, &results); err != nil {
+		return Hubs{}, err
+	}
+
+	return results, nil
+}
+
 // GetMetadataChildren can get a show's season titles. My use-case would be getting the season titles after using Search()
 func (p *Plex) GetMetadataChildren(key string) (MetadataChildren, error) {
 	if key == "" {
@@ -323,6 +502,78 @@ func (p *Plex) GetOnDeck() (SearchResultsEpisode, error) {
 	return results, nil
 }
 
+// GetContinueWatching gets the partially watched items shown in the "Continue Watching" hub for the user of the current token.
+func (p *Plex) GetContinueWatching() (SearchResultsEpisode, error) {
+	query := fmt.Sprintf("%s/hubs/continueWatching/items", p.URL)
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return SearchResultsEpisode{}, err
+	}
+
+	defer resp.Body.Close()
+
+	// Unauthorized
+	if resp.StatusCode == http.StatusUnauthorized {
+		return SearchResultsEpisode{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return SearchResultsEpisode{}, errors.New(resp.Status)
+	}
+
+	var results SearchResultsEpisode
+
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return SearchResultsEpisode{}, err
+	}
+
+	return results, nil
+}
+
+// GetRecentlyAdded gets the most recently added items of a library section, or of every section when sectionKey is empty.
+// A limit greater than 0 caps the number of items returned.
+func (p *Plex) GetRecentlyAdded(sectionKey string, limit int) (SearchResultsEpisode, error) {
+	query := fmt.Sprintf("%s/library/recentlyAdded", p.URL)
+
+	if sectionKey != "" {
+		query = fmt.Sprintf("%s/library/sections/%s/recentlyAdded", p.URL, sectionKey)
+	}
+
+	h := p.Headers
+
+	if limit > 0 {
+		h.ContainerStart = "0"
+		h.ContainerSize = strconv.Itoa(limit)
+	}
+
+	resp, err := p.get(query, h)
+
+	if err != nil {
+		return SearchResultsEpisode{}, err
+	}
+
+	defer resp.Body.Close()
+
+	// Unauthorized
+	if resp.StatusCode == http.StatusUnauthorized {
+		return SearchResultsEpisode{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return SearchResultsEpisode{}, errors.New(resp.Status)
+	}
+
+	var results SearchResultsEpisode
+
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return SearchResultsEpisode{}, err
+	}
+
+	sort.SliceStable(results.MediaContainer.Metadata, func(i, j int) bool {
+		return results.MediaContainer.Metadata[i].AddedAt > results.MediaContainer.Metadata[j].AddedAt
+	})
+
+	return results, nil
+}
+
 // Download media associated with metadata
 func (p *Plex) Download(meta Metadata, path string, createFolders bool, skipIfExists bool) error {
 
@@ -389,258 +640,374 @@ func (p *Plex) Download(meta Metadata, path string, createFolders bool, skipIfEx
 	return nil
 }
 
-// GetPlaylist gets all videos in a playlist.
-func (p *Plex) GetPlaylist(key int) (SearchResultsEpisode, error) {
-	query := fmt.Sprintf("%s/playlists/%d/items", p.URL, key)
+// DownloadMedia streams the original file of a media part to w. A positive offset resumes
+// the download from that byte, or returns ErrRangeNotSupported when the server can not resume it.
+// It returns the number of bytes written.
+//
+// progress is optional and is called as the file is written with the bytes written so far and
+// the total size of the file, both including offset. total is -1 when the server does not send a Content-Length.
+func (p *Plex) DownloadMedia(part Part, w io.Writer, offset int64, progress func(bytesWritten, totalBytes int64)) (int64, error) {
+	if part.Key == "" {
+		return 0, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
 
-	resp, err := p.get(query, p.Headers)
+	query := fmt.Sprintf("%s%s?download=1", p.URL, part.Key)
 
-	if err != nil {
-		return SearchResultsEpisode{}, err
+	h := p.Headers
+
+	if offset > 0 {
+		h.Range = fmt.Sprintf("bytes=%d-", offset)
 	}
 
-	// Unauthorized
-	if resp.StatusCode == http.StatusUnauthorized {
-		return SearchResultsEpisode{}, errors.New(ErrorNotAuthorized)
+	resp, err := p.grab(query, h)
+
+	if err != nil {
+		return 0, err
 	}
 
 	defer resp.Body.Close()
 
-	var results SearchResultsEpisode
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// the server ignored the range and is sending the whole file
+		if offset > 0 {
+			return 0, ErrRangeNotSupported
+		}
+	case http.StatusPartialContent:
+	case http.StatusUnauthorized:
+		return 0, errors.New(ErrorNotAuthorized)
+	default:
+		return 0, fmt.Errorf(ErrorServer, resp.Status)
+	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		return SearchResultsEpisode{}, err
+	if progress == nil {
+		return io.Copy(w, resp.Body)
 	}
 
-	return results, nil
-}
+	total := int64(-1)
 
-// GetThumbnail returns the response of a request to pms thumbnail
-// My ideal use case would be to proxy a request to pms without exposing the plex token
-func (p *Plex) GetThumbnail(key, thumbnailID string) (*http.Response, error) {
-	query := fmt.Sprintf("%s/library/metadata/%s/thumb/%s", p.URL, key, thumbnailID)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
 
-	return p.get(query, p.Headers)
+	return io.Copy(&progressWriter{w: w, written: offset, total: total, progress: progress}, resp.Body)
 }
 
-// Test your connection to your Plex Media Server
-func (p *Plex) Test() (bool, error) {
-	resp, err := p.get(plexURL+"/api/servers", p.Headers)
+// DownloadLogs streams the zip archive of the server logs to w and returns the number of bytes written.
+// Only the server owner can download the logs: other tokens get ErrorNotAuthorized.
+func (p *Plex) DownloadLogs(w io.Writer) (int64, error) {
+	query := fmt.Sprintf("%s/diagnostics/logs", p.URL)
+
+	resp, err := p.grab(query, p.Headers)
 
 	if err != nil {
-		return false, err
+		return 0, err
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return false, errors.New(ErrorNotAuthorized)
-	} else if resp.StatusCode != http.StatusOK {
-		return false, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return 0, errors.New(ErrorNotAuthorized)
+	default:
+		return 0, fmt.Errorf(ErrorServer, resp.Status)
 	}
 
-	return true, nil
+	return io.Copy(w, resp.Body)
 }
 
-// KillTranscodeSession stops a transcode session
-func (p *Plex) KillTranscodeSession(sessionKey string) (bool, error) {
+// ImageURL returns an authenticated url to a resized image that can be handed to a browser. thumbPath may
+// be any of Metadata's Thumb, Art or GrandparentThumb, or an absolute url.
+func (p *Plex) ImageURL(thumbPath string, width, height int) string {
+	params := url.Values{}
+	params.Set("url", thumbPath)
+	params.Set("width", strconv.Itoa(width))
+	params.Set("height", strconv.Itoa(height))
+	params.Set("X-Plex-Token", p.Token)
 
-	if sessionKey == "" {
-		return false, errors.New(ErrorMissingSessionKey)
+	return fmt.Sprintf("%s/photo/:/transcode?%s", p.URL, params.Encode())
+}
+
+// PosterURL returns an authenticated url to the poster of m, or "" when it has none
+func (p *Plex) PosterURL(m Metadata) string {
+	return p.mediaURL(m.Thumb)
+}
+
+// ArtURL returns an authenticated url to the background art of m, or of its show, or "" when it has none
+func (p *Plex) ArtURL(m Metadata) string {
+	if m.Art == "" {
+		return p.mediaURL(m.GrandparentArt)
 	}
 
-	query := p.URL + "/video/:/transcode/universal/stop?session=" + sessionKey
+	return p.mediaURL(m.Art)
+}
 
-	resp, err := p.get(query, p.Headers)
+// BannerURL returns an authenticated url to the banner of a show, or "" when it has none
+func (p *Plex) BannerURL(m Metadata) string {
+	return p.mediaURL(m.Banner)
+}
 
-	if err != nil {
-		return false, err
+// ThemeURL returns an authenticated url to the theme music of m, or of its show, or "" when it has none
+func (p *Plex) ThemeURL(m Metadata) string {
+	if m.Theme == "" {
+		return p.mediaURL(m.GrandparentTheme)
 	}
 
-	defer resp.Body.Close()
+	return p.mediaURL(m.Theme)
+}
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return false, errors.New(ErrorNotAuthorized)
-	} else if resp.StatusCode != http.StatusOK {
-		return false, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+// LogoURL returns an authenticated url to the clear logo of m, or "" when it has none
+func (p *Plex) LogoURL(m Metadata) string {
+	for _, image := range m.Image {
+		if image.Type == "clearLogo" {
+			return p.mediaURL(image.URL)
+		}
 	}
 
-	return true, nil
+	return ""
 }
 
-// GetTranscodeSessions retrieves a list of all active transcode sessions
-func (p *Plex) GetTranscodeSessions() (TranscodeSessionsResponse, error) {
-	var result TranscodeSessionsResponse
+// mediaURL returns an authenticated url to path on the server. Absolute urls are returned as is
+// so that the token is not sent to other hosts.
+func (p *Plex) mediaURL(path string) string {
+	if path == "" {
+		return ""
+	}
 
-	query := p.URL + "/transcode/sessions"
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
 
-	resp, err := p.get(query, p.Headers)
+	return fmt.Sprintf("%s%s?X-Plex-Token=%s", p.URL, path, url.QueryEscape(p.Token))
+}
+
+// GetImage has the server resize an image such as Metadata's Thumb, Art or GrandparentThumb. path may
+// be relative to the server (/library/metadata/1/thumb/1) or an absolute url.
+func (p *Plex) GetImage(path string, width, height int) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	h := p.Headers
+	h.Accept = "image/*"
+
+	resp, err := p.get(p.ImageURL(path, width, height), h)
 
 	if err != nil {
-		return result, err
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return result, errors.New(ErrorNotAuthorized)
+		return nil, errors.New(ErrorNotAuthorized)
 	} else if resp.StatusCode != http.StatusOK {
-		return result, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+		return nil, fmt.Errorf(ErrorServer, resp.Status)
 	}
 
-	return result, json.NewDecoder(resp.Body).Decode(&result)
-
+	return ioutil.ReadAll(resp.Body)
 }
 
-// GetPlexTokens not sure if it works
-func (p *Plex) GetPlexTokens(token string) (DevicesResponse, error) {
-	var result DevicesResponse
+// SetDefaultStream selects the audio or subtitle stream plex should use when playing a part. streamType
+// is StreamTypeAudio or StreamTypeSubtitle. Use a streamID of 0 with StreamTypeSubtitle to turn subtitles off.
+func (p *Plex) SetDefaultStream(partID int, streamType StreamType, streamID int) error {
+	var param string
 
-	query := plexURL + "/devices.json"
+	switch streamType {
+	case StreamTypeAudio:
+		param = "audioStreamID"
+	case StreamTypeSubtitle:
+		param = "subtitleStreamID"
+	default:
+		return fmt.Errorf("stream type must be audio (%d) or subtitle (%d)", StreamTypeAudio, StreamTypeSubtitle)
+	}
 
-	resp, err := p.get(query, p.Headers)
+	if partID == 0 {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	query := fmt.Sprintf("%s/library/parts/%d?%s=%d&allParts=1", p.URL, partID, param, streamID)
+
+	resp, err := p.put(query, nil, p.Headers)
 
 	if err != nil {
-		return result, err
+		return err
 	}
 
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return result, errors.New(ErrorNotAuthorized)
+		return errors.New(ErrorNotAuthorized)
 	} else if resp.StatusCode != http.StatusOK {
-		return result, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
-	return result, json.NewDecoder(resp.Body).Decode(&result)
+	return nil
 }
 
-// DeletePlexToken is currently not tested
-func (p *Plex) DeletePlexToken(token string) (bool, error) {
-	var result bool
-
-	query := plexURL + "/devices/" + token + ".json"
+// DisableSubtitles turns off subtitles for a part
+func (p *Plex) DisableSubtitles(partID int) error {
+	return p.SetDefaultStream(partID, StreamTypeSubtitle, 0)
+}
 
-	resp, err := p.get(query, p.Headers)
+// UploadSubtitle attaches a local subtitle file (.srt, .ass or .vtt) to a piece of media. language
+// is the subtitle's language code, i.e. "en"
+func (p *Plex) UploadSubtitle(ratingKey, language, filePath string) error {
+	file, err := os.Open(filePath)
 
 	if err != nil {
-		return result, err
+		return err
 	}
 
-	defer resp.Body.Close()
+	defer file.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return result, errors.New(ErrorNotAuthorized)
-	} else if resp.StatusCode != http.StatusOK {
-		return result, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	return p.UploadSubtitleReader(ratingKey, language, filepath.Base(filePath), file)
+}
+
+// UploadSubtitleReader attaches a subtitle read from r to a piece of media. The subtitle's codec is
+// taken from the extension of fileName.
+func (p *Plex) UploadSubtitleReader(ratingKey, language, fileName string, r io.Reader) error {
+	if ratingKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
 	}
 
-	return result, json.NewDecoder(resp.Body).Decode(&result)
-}
+	codec := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
 
-// GetFriends returns all of your plex friends
-func (p *Plex) GetFriends() ([]Friends, error) {
+	switch codec {
+	case "srt", "ass", "vtt":
+	default:
+		return fmt.Errorf("unsupported subtitle format: %q", codec)
+	}
 
-	var plexFriendsResp friendsResponse
+	body, err := ioutil.ReadAll(r)
 
-	query := plexURL + "/api/users"
+	if err != nil {
+		return err
+	}
 
-	newHeaders := p.Headers
+	params := url.Values{}
+	params.Set("title", fileName)
+	params.Set("language", language)
+	params.Set("codec", codec)
+	params.Set("format", codec)
 
-	newHeaders.Accept = "application/xml"
+	query := fmt.Sprintf("%s/library/metadata/%s/subtitles?%s", p.URL, ratingKey, params.Encode())
 
-	resp, err := p.get(query, newHeaders)
+	h := p.Headers
+	h.Accept = "text/plain, */*"
+	h.ContentType = "text/plain"
+
+	resp, err := p.post(query, body, h)
 
 	if err != nil {
-		return []Friends{}, err
+		return err
 	}
 
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return []Friends{}, errors.New(ErrorNotAuthorized)
+		return errors.New(ErrorNotAuthorized)
 	} else if resp.StatusCode != http.StatusOK {
-		return []Friends{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
-	respBytes, err := ioutil.ReadAll(resp.Body)
-
-	if err != nil {
-		return []Friends{}, err
-	}
+	return nil
+}
 
-	err = xml.Unmarshal(respBytes, &plexFriendsResp)
+// GetPlaylist gets all videos in a playlist.
+func (p *Plex) GetPlaylist(key int) (SearchResultsEpisode, error) {
+	query := fmt.Sprintf("%s/playlists/%d/items", p.URL, key)
+
+	resp, err := p.get(query, p.Headers)
 
 	if err != nil {
-		return []Friends{}, err
+		return SearchResultsEpisode{}, err
 	}
 
-	friendCount := plexFriendsResp.Size
+	// Unauthorized
+	if resp.StatusCode == http.StatusUnauthorized {
+		return SearchResultsEpisode{}, errors.New(ErrorNotAuthorized)
+	}
 
-	plexFriends := make([]Friends, friendCount)
+	defer resp.Body.Close()
 
-	for ii, f := range plexFriendsResp.User {
-		plexFriends[ii] = f
+	var results SearchResultsEpisode
+
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return SearchResultsEpisode{}, err
 	}
 
-	return plexFriends, nil
+	return results, nil
 }
 
-// RemoveFriend from your friend's list which stops access to your Plex server
-func (p *Plex) RemoveFriend(id string) (bool, error) {
-
-	query := plexURL + "/api/friends/" + id
+// GetPlaylists returns all playlists on your server
+func (p *Plex) GetPlaylists() (Playlists, error) {
+	query := fmt.Sprintf("%s/playlists", p.URL)
 
-	resp, err := p.delete(query, p.Headers)
+	resp, err := p.get(query, p.Headers)
 
 	if err != nil {
-		return false, err
+		return Playlists{}, err
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
-		return false, errors.New(resp.Status)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return Playlists{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return Playlists{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
-	result := new(resultResponse)
+	var results Playlists
 
-	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
-		return false, err
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return Playlists{}, err
 	}
 
-	return result.Response.Code == 0, nil
+	return results, nil
 }
 
-// InviteFriend to access your Plex server. Add restrictions to media or give them full access.
-func (p *Plex) InviteFriend(params InviteFriendParams) error {
-
-	label := url.QueryEscape(params.Label)
+// GetPlaylistItems returns the items of a playlist via the playlist's rating key
+func (p *Plex) GetPlaylistItems(playlistKey string) (SearchResultsEpisode, error) {
+	if playlistKey == "" {
+		return SearchResultsEpisode{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
 
-	query := fmt.Sprintf("%s/api/v2/shared_servers", plexURL)
+	query := fmt.Sprintf("%s/playlists/%s/items", p.URL, playlistKey)
 
-	var requestBody inviteFriendBody
+	resp, err := p.get(query, p.Headers)
 
-	requestBody.MachineIdentifier = params.MachineID
-	requestBody.InvitedEmail = params.UsernameOrEmail
-	requestBody.LibrarySectionIDs = params.LibraryIDs
+	if err != nil {
+		return SearchResultsEpisode{}, err
+	}
 
-	settings := inviteFriendSettings{}
+	defer resp.Body.Close()
 
-	if label != "" {
-		settings.FilterMovies = fmt.Sprintf("label=%s", label)
-		settings.FilterTelevision = fmt.Sprintf("label=%s", label)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return SearchResultsEpisode{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return SearchResultsEpisode{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
-	requestBody.Settings = settings
+	var results SearchResultsEpisode
 
-	jsonBody, jsonErr := json.Marshal(requestBody)
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return SearchResultsEpisode{}, err
+	}
 
-	if jsonErr != nil {
-		return jsonErr
+	return results, nil
+}
+
+// DeletePlaylist removes a playlist from your server via the playlist's rating key
+func (p *Plex) DeletePlaylist(playlistKey string) error {
+	if playlistKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
 	}
 
-	resp, err := p.post(query, jsonBody, p.Headers)
+	query := fmt.Sprintf("%s/playlists/%s", p.URL, playlistKey)
+
+	resp, err := p.delete(query, p.Headers)
 
 	if err != nil {
 		return err
@@ -648,279 +1015,563 @@ func (p *Plex) InviteFriend(params InviteFriendParams) error {
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
-		return errors.New(resp.Status)
-	}
-
-	result := new(inviteFriendResponse)
-
-	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-		return err
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
 	return nil
 }
 
-// UpdateFriendAccess limit your friends access to your plex server
-func (p *Plex) UpdateFriendAccess(userID string, params UpdateFriendParams) (bool, error) {
-	// Fix any defaults to statisfy what plex expects
-	if params.AllowSync == "" {
-		params.AllowSync = "0"
-	}
-
-	if params.AllowCameraUpload == "" {
-		params.AllowCameraUpload = "0"
+// AddToPlaylist appends media to an existing playlist via their rating keys
+func (p *Plex) AddToPlaylist(playlistKey string, ratingKeys []int) error {
+	if playlistKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
 	}
 
-	if params.AllowChannels == "" {
-		params.AllowChannels = "0"
+	if len(ratingKeys) == 0 {
+		return errors.New("at least one rating key is required")
 	}
 
-	query := fmt.Sprintf("%s/api/friends/%s", plexURL, userID)
-
-	parsedQuery, parseErr := url.Parse(query)
+	uri, err := p.libraryURI(ratingKeys)
 
-	if parseErr != nil {
-		return false, parseErr
+	if err != nil {
+		return err
 	}
 
-	vals := parsedQuery.Query()
-
-	vals.Add("allowSync", params.AllowSync)
-	vals.Add("allowCameraUpload", params.AllowCameraUpload)
-	vals.Add("allowChannels", params.AllowChannels)
-	vals.Add("filterMovies", params.FilterMovies)
-	vals.Add("filterMusic", params.FilterMusic)
-	vals.Add("filterTelevision", params.FilterTelevision)
-	vals.Add("filterPhotos", params.FilterPhotos)
-
-	parsedQuery.RawQuery = vals.Encode()
-
-	query = parsedQuery.String()
+	query := fmt.Sprintf("%s/playlists/%s/items?uri=%s", p.URL, playlistKey, url.QueryEscape(uri))
 
 	resp, err := p.put(query, nil, p.Headers)
 
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	resp.Body.Close()
+	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return false, errors.New(resp.Status)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
-	return true, nil
+	return nil
 }
 
-// RemoveFriendAccessToLibrary you can individually revoke access to a library on your server. Such as movies, tv shows, music, etc
-func (p *Plex) RemoveFriendAccessToLibrary(userID, machineID, serverID string) (bool, error) {
-	query := fmt.Sprintf("%s/api/servers/%s/shared_servers/%s", plexURL, machineID, serverID)
+// RemoveFromPlaylist removes an item from a playlist. playlistItemID is the id of the item
+// inside of the playlist (Metadata.PlaylistItemID from GetPlaylistItems), not its rating key
+func (p *Plex) RemoveFromPlaylist(playlistKey string, playlistItemID int) error {
+	if playlistKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	query := fmt.Sprintf("%s/playlists/%s/items/%d", p.URL, playlistKey, playlistItemID)
 
 	resp, err := p.delete(query, p.Headers)
 
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	resp.Body.Close()
+	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return false, errors.New(resp.Status)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
-	return true, nil
+	return nil
 }
 
-// CheckUsernameOrEmail will check if the username is a Plex user or will verify an email is valid
-func (p *Plex) CheckUsernameOrEmail(usernameOrEmail string) (bool, error) {
+// LibraryURI returns the uri referencing library items that plex expects, i.e. to create a play queue
+func (p *Plex) LibraryURI(ratingKeys ...int) (string, error) {
+	if len(ratingKeys) == 0 {
+		return "", fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
 
-	usernameOrEmail = url.QueryEscape(usernameOrEmail)
+	return p.libraryURI(ratingKeys)
+}
 
-	query := fmt.Sprintf("%s/api/users/validate?invited_email=%s", plexURL, usernameOrEmail)
+// CreatePlayQueue creates a play queue of queueType (i.e. PlayQueueTypeAudio) from the items of uri
+// (see LibraryURI). Use the uri of an album to play the album.
+func (p *Plex) CreatePlayQueue(queueType, uri string, shuffle bool) (PlayQueue, error) {
+	switch queueType {
+	case PlayQueueTypeVideo, PlayQueueTypeAudio, PlayQueueTypePhoto:
+	default:
+		return PlayQueue{}, fmt.Errorf("invalid play queue type: %s", queueType)
+	}
 
-	resp, err := p.post(query, nil, p.Headers)
+	if uri == "" {
+		return PlayQueue{}, errors.New("uri is required")
+	}
+
+	params := url.Values{}
+	params.Set("type", queueType)
+	params.Set("uri", uri)
+	params.Set("shuffle", "0")
+	params.Set("continuous", "0")
+	params.Set("repeat", "0")
+
+	if shuffle {
+		params.Set("shuffle", "1")
+	}
+
+	resp, err := p.post(fmt.Sprintf("%s/playQueues?%s", p.URL, params.Encode()), nil, p.Headers)
 
 	if err != nil {
-		return false, err
+		return PlayQueue{}, err
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
-		return false, errors.New(resp.Status)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return PlayQueue{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return PlayQueue{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
-	result := new(resultResponse)
+	var result PlayQueue
 
-	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
-		return false, err
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return PlayQueue{}, err
 	}
 
-	return result.Response.Code == 0, nil
+	return result, nil
 }
 
-// StopPlayback acts as a remote controller and sends the 'stop' command
-func (p *Plex) StopPlayback(machineID string) error {
-	query := p.URL + "/player/playback/stop"
-
-	newHeaders := p.Headers
-
-	newHeaders.Accept = "application/xml"
-	newHeaders.TargetClientIdentifier = machineID
+// optimizedPlaylistType is the playlist type of the background processing playlist plex keeps the
+// optimize jobs in
+const optimizedPlaylistType = 42
 
-	resp, err := p.get(query, newHeaders)
+// OptimizeMedia creates an optimized version of a piece of media next to the original, using
+// targetTagID as quality preset (i.e. OptimizeTargetMobile). It returns the id of the optimize job,
+// whose progress is sent as background processing queue notifications.
+//
+// The job is added to the background processing playlist, the way Plex Web optimizes media, as
+// servers do not create optimize jobs through POST /library/optimize.
+func (p *Plex) OptimizeMedia(ratingKey string, targetTagID int) (int, error) {
+	key, err := strconv.Atoi(ratingKey)
 
 	if err != nil {
-		return err
+		return 0, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf(resp.Status)
+	switch targetTagID {
+	case OptimizeTargetMobile, OptimizeTargetTV, OptimizeTargetOriginal:
+	default:
+		return 0, fmt.Errorf("invalid optimize target: %d", targetTagID)
 	}
 
-	return nil
-}
-
-// GetServerDevices returns a list of your Plex devices (servers, players, controllers, etc)
-func (p *Plex) GetDevices() ([]PMSDevices, error) {
-	query := plexURL + "/devices.xml"
-
-	resp, err := p.get(query, p.Headers)
+	metadata, err := p.GetMetadata(ratingKey)
 
 	if err != nil {
-		return []PMSDevices{}, err
+		return 0, err
 	}
 
-	defer resp.Body.Close()
+	if len(metadata.MediaContainer.Metadata) == 0 {
+		return 0, fmt.Errorf("no media with the key %s", ratingKey)
+	}
 
-	result := new(resourcesResponse)
+	uri, err := p.libraryURI([]int{key})
 
-	if resp.StatusCode != http.StatusOK {
-		return []PMSDevices{}, errors.New(resp.Status)
+	if err != nil {
+		return 0, err
 	}
 
-	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
-		fmt.Println(err.Error())
+	itemsURL, err := p.optimizedItemsURL()
 
-		return []PMSDevices{}, err
+	if err != nil {
+		return 0, err
 	}
 
-	return result.Device, nil
-}
+	title := metadata.MediaContainer.Metadata[0].Title
 
-// GetServerDevices returns a list of Plex servers with some added info, notably connection data
-func (p *Plex) GetServerDevices() ([]PMSDevices, error) {
-	query := plexURL + "/api/resources?includeHttps=1&includeRelay=1&includeIPv6=1"
+	params := url.Values{}
+	params.Set("Item[type]", strconv.Itoa(optimizedPlaylistType))
+	params.Set("Item[title]", title)
+	params.Set("Item[target]", "")
+	params.Set("Item[targetTagID]", strconv.Itoa(targetTagID))
+	params.Set("Item[locationID]", "-1")
+	params.Set("Item[Policy][scope]", "all")
+	params.Set("Item[Policy][value]", "")
+	params.Set("Item[Policy][unwatched]", "0")
+	params.Set("Item[Location][uri]", uri)
 
-	resp, err := p.get(query, p.Headers)
+	resp, err := p.put(itemsURL+"?"+params.Encode(), nil, p.Headers)
 
 	if err != nil {
-		return []PMSDevices{}, err
+		return 0, err
 	}
 
 	defer resp.Body.Close()
 
-	result := new(resourcesResponse)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return 0, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return []PMSDevices{}, errors.New(resp.Status)
+	var result optimizedItemsResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
 	}
 
-	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
-		fmt.Println(err.Error())
+	// the server returns every job, which may include jobs created concurrently
+	id := 0
 
-		return []PMSDevices{}, err
+	for _, item := range result.items() {
+		if item.ID > id && item.TargetTagID == targetTagID && item.optimizes(uri, title) {
+			id = item.ID
+		}
 	}
 
-	return result.Device, nil
+	if id == 0 {
+		return 0, errors.New("server did not return the optimize job")
+	}
+
+	return id, nil
 }
 
-// GetServers returns a list of your Plex servers
-func (p *Plex) GetServers() ([]PMSDevices, error) {
+// optimizedItemsURL returns the url of the items of the background processing playlist, which holds
+// the optimize jobs
+func (p *Plex) optimizedItemsURL() (string, error) {
+	var result Playlists
 
-	// we can use the https://<pms-ip>/media/providers endpoint
-	// but if the caller does not know the ip beforehand, we can grab it
-	// from plex.tv so we'll use https://plex.tv endpoint to give that option
+	query := fmt.Sprintf("%s/playlists?type=%d", p.URL, optimizedPlaylistType)
 
-	devices, err := p.GetServerDevices()
+	if err := p.getJSON(query, &result); err != nil {
+		return "", err
+	}
 
-	if err != nil {
-		return devices, err
+	for _, playlist := range result.MediaContainer.Metadata {
+		if playlist.RatingKey != "" {
+			return fmt.Sprintf("%s/playlists/%s/items", p.URL, playlist.RatingKey), nil
+		}
 	}
 
-	// filter devices for servers
-	var filteredDevices []PMSDevices
+	return "", errors.New("server has no background processing playlist")
+}
 
-	for _, r := range devices {
-		if r.Provides != "server" {
-			continue
-		}
+// GetOptimizedVersions returns the optimize jobs of the server
+func (p *Plex) GetOptimizedVersions() ([]OptimizedItem, error) {
+	itemsURL, err := p.optimizedItemsURL()
 
-		filteredDevices = append(filteredDevices, r)
+	if err != nil {
+		return nil, err
 	}
 
-	return filteredDevices, nil
+	var result optimizedItemsResponse
+
+	if err := p.getJSON(itemsURL, &result); err != nil {
+		return nil, err
+	}
+
+	return result.items(), nil
 }
 
-// GetServersInfo returns info about all of your Plex servers
-func (p *Plex) GetServersInfo() (ServerInfo, error) {
-	query := plexURL + "/api/servers"
+// DeleteOptimizedVersion deletes an optimize job and the optimized versions it created
+func (p *Plex) DeleteOptimizedVersion(id int) error {
+	itemsURL, err := p.optimizedItemsURL()
 
-	resp, err := p.get(query, p.Headers)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.delete(fmt.Sprintf("%s/%d", itemsURL, id), p.Headers)
 
 	if err != nil {
-		return ServerInfo{}, err
+		return err
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return ServerInfo{}, errors.New(resp.Status)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
-	result := ServerInfo{}
+	return nil
+}
 
-	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
-		fmt.Println(err.Error())
+// GetPlayQueue returns the items of a play queue and the id of the item being played
+func (p *Plex) GetPlayQueue(playQueueID int) (PlayQueue, error) {
+	var result PlayQueue
 
-		return ServerInfo{}, err
+	if err := p.getJSON(fmt.Sprintf("%s/playQueues/%d", p.URL, playQueueID), &result); err != nil {
+		return PlayQueue{}, err
 	}
 
 	return result, nil
 }
 
-// GetMachineID returns the machine id of the server with the associated access token
-func (p *Plex) GetMachineID() (string, error) {
-	if p.Token == "" {
-		return "", errors.New("a token is required to fetch machine id")
-	}
-
-	servers, err := p.GetServersInfo()
+// libraryURI builds the server:// uri plex expects when referencing library items
+func (p *Plex) libraryURI(ratingKeys []int) (string, error) {
+	machineID, err := p.serverMachineID()
 
 	if err != nil {
 		return "", err
 	}
 
-	var machineID string
+	keys := make([]string, len(ratingKeys))
 
-	for _, server := range servers.Server {
-		if server.AccessToken == p.Token {
-			machineID = server.MachineIdentifier
-		}
+	for ii, key := range ratingKeys {
+		keys[ii] = strconv.Itoa(key)
 	}
 
-	if machineID == "" {
+	return fmt.Sprintf("server://%s/com.plexapp.plugins.library/library/metadata/%s", machineID, strings.Join(keys, ",")), nil
+}
+
+// serverMachineID returns the machine identifier of the server at p.URL. Unlike GetMachineID, which looks
+// the server up on plex.tv by its access token and so only finds servers you own, it asks the server
+// itself: it works with shared servers and home user tokens, and does not depend on plex.tv being reachable.
+func (p *Plex) serverMachineID() (string, error) {
+	resp, err := p.get(p.URL+"/", p.Headers)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	var result BaseAPIResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	if result.MediaContainer.MachineIdentifier == "" {
 		return "", errors.New("could not fetch machine id")
 	}
 
-	return machineID, nil
+	return result.MediaContainer.MachineIdentifier, nil
 }
 
-// GetSections of your plex server. This is useful when inviting a user
-// as you can restrict the invited user to a library (i.e. Movie's, TV Shows)
-func (p *Plex) GetSections(machineID string) ([]ServerSections, error) {
-	query := fmt.Sprintf("%s/api/servers/%s", plexURL, machineID)
+// GetServerCapabilities returns the identity of the server and the features it supports
+func (p *Plex) GetServerCapabilities() (ServerCapabilities, error) {
+	var result serverCapabilitiesResponse
+
+	if err := p.getJSON(p.URL+"/", &result); err != nil {
+		return ServerCapabilities{}, err
+	}
+
+	c := result.MediaContainer
+	sessions, _ := c.TranscoderActiveVideoSessions.Int64()
+
+	return ServerCapabilities{
+		AllowCameraUpload:             bool(c.AllowCameraUpload),
+		AllowChannelAccess:            bool(c.AllowChannelAccess),
+		AllowSharing:                  bool(c.AllowSharing),
+		AllowSync:                     bool(c.AllowSync),
+		BackgroundProcessing:          bool(c.BackgroundProcessing),
+		Certificate:                   bool(c.Certificate),
+		CompanionProxy:                bool(c.CompanionProxy),
+		EventStream:                   bool(c.EventStream),
+		FriendlyName:                  c.FriendlyName,
+		HubSearch:                     bool(c.HubSearch),
+		ItemClusters:                  bool(c.ItemClusters),
+		MachineIdentifier:             c.MachineIdentifier,
+		MediaProviders:                bool(c.MediaProviders),
+		Multiuser:                     bool(c.Multiuser),
+		MyPlex:                        bool(c.MyPlex),
+		MyPlexSubscription:            bool(c.MyPlexSubscription),
+		MyPlexUsername:                c.MyPlexUsername,
+		PhotoAutoTag:                  bool(c.PhotoAutoTag),
+		Platform:                      c.Platform,
+		PlatformVersion:               c.PlatformVersion,
+		PluginHost:                    bool(c.PluginHost),
+		ReadOnlyLibraries:             bool(c.ReadOnlyLibraries),
+		Sync:                          bool(c.Sync),
+		TranscoderActiveVideoSessions: int(sessions),
+		TranscoderAudio:               bool(c.TranscoderAudio),
+		TranscoderLyrics:              bool(c.TranscoderLyrics),
+		TranscoderPhoto:               bool(c.TranscoderPhoto),
+		TranscoderSubtitles:           bool(c.TranscoderSubtitles),
+		TranscoderVideo:               bool(c.TranscoderVideo),
+		Updater:                       bool(c.Updater),
+		Version:                       c.Version,
+		VoiceSearch:                   bool(c.VoiceSearch),
+	}, nil
+}
+
+// GetThumbnail returns the response of a request to pms thumbnail
+// My ideal use case would be to proxy a request to pms without exposing the plex token
+func (p *Plex) GetThumbnail(key, thumbnailID string) (*http.Response, error) {
+	query := fmt.Sprintf("%s/library/metadata/%s/thumb/%s", p.URL, key, thumbnailID)
+
+	return p.get(query, p.Headers)
+}
+
+// Test your connection to your Plex Media Server
+func (p *Plex) Test() (bool, error) {
+	resp, err := p.get(p.plexTVURL()+"/api/servers", p.Headers)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return false, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return true, nil
+}
+
+// Ping checks that your Plex Media Server is reachable and returns the round trip time of the
+// request. It requests /identity, which is cheap and usually does not require a token. The request
+// is sent once, without waiting for the rate limiter, so that the time is not skewed.
+func (p *Plex) Ping() (time.Duration, error) {
+	req, err := http.NewRequestWithContext(p.requestContext(), http.MethodGet, p.URL+"/identity", nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Add("Accept", p.Headers.Accept)
+	req.Header.Add("X-Plex-Client-Identifier", p.ClientIdentifier)
+
+	if p.Token != "" {
+		req.Header.Add("X-Plex-Token", p.Token)
+	}
+
+	start := time.Now()
+
+	resp, err := p.HTTPClient.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+
+	latency := time.Since(start)
+
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return 0, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return latency, nil
+}
+
+// KillTranscodeSession stops a transcode session
+func (p *Plex) KillTranscodeSession(sessionKey string) (bool, error) {
+
+	if sessionKey == "" {
+		return false, errors.New(ErrorMissingSessionKey)
+	}
+
+	query := p.URL + "/video/:/transcode/universal/stop?session=" + sessionKey
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return false, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return true, nil
+}
+
+// GetTranscodeSessions retrieves a list of all active transcode sessions, see TranscodeSessionsResponse.Sessions
+func (p *Plex) GetTranscodeSessions() (TranscodeSessionsResponse, error) {
+	var result TranscodeSessionsResponse
+
+	query := p.URL + "/transcode/sessions"
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return result, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return result, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return result, json.NewDecoder(resp.Body).Decode(&result)
+
+}
+
+// GetPlexTokens not sure if it works
+//
+// Deprecated: DevicesResponse only holds a few fields of a single device. Use GetDevices instead.
+func (p *Plex) GetPlexTokens(token string) (DevicesResponse, error) {
+	var result DevicesResponse
+
+	query := p.plexTVURL() + "/devices.json"
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return result, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return result, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return result, json.NewDecoder(resp.Body).Decode(&result)
+}
+
+// DeletePlexToken is currently not tested
+func (p *Plex) DeletePlexToken(token string) (bool, error) {
+	var result bool
+
+	query := p.plexTVURL() + "/devices/" + token + ".json"
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return result, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return result, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return result, json.NewDecoder(resp.Body).Decode(&result)
+}
+
+// GetFriends returns all of your plex friends
+func (p *Plex) GetFriends() ([]Friends, error) {
+
+	var plexFriendsResp friendsResponse
+
+	query := p.plexTVURL() + "/api/users"
 
 	newHeaders := p.Headers
 
@@ -929,295 +1580,2204 @@ func (p *Plex) GetSections(machineID string) ([]ServerSections, error) {
 	resp, err := p.get(query, newHeaders)
 
 	if err != nil {
-		return []ServerSections{}, err
+		return []Friends{}, err
 	}
 
 	defer resp.Body.Close()
 
-	var result SectionIDResponse
+	if resp.StatusCode == http.StatusUnauthorized {
+		return []Friends{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return []Friends{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return []Friends{}, err
+	}
+
+	err = xml.Unmarshal(respBytes, &plexFriendsResp)
+
+	if err != nil {
+		return []Friends{}, err
+	}
+
+	friendCount := plexFriendsResp.Size
+
+	plexFriends := make([]Friends, friendCount)
+
+	for ii, f := range plexFriendsResp.User {
+		plexFriends[ii] = f
+	}
+
+	return plexFriends, nil
+}
+
+// RemoveFriend from your friend's list which stops access to your Plex server.
+// id is the friend's id (Friends.ID). If plex refuses the removal the error contains plex's status message
+func (p *Plex) RemoveFriend(id string) (bool, error) {
+	if id == "" {
+		return false, errors.New("a friend id is required")
+	}
+
+	query := p.plexTVURL() + "/api/friends/" + id
+
+	newHeaders := p.Headers
+
+	newHeaders.Accept = "application/xml"
+
+	resp, err := p.delete(query, newHeaders)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return false, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return false, errors.New(resp.Status)
+	}
+
+	result := new(resultResponse)
+
+	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
+		return false, err
+	}
+
+	if result.Code != 0 {
+		return false, fmt.Errorf("failed to remove friend: %s", result.Status)
+	}
+
+	return true, nil
+}
+
+// InviteFriend to access your Plex server. Add restrictions to media or give them full access.
+func (p *Plex) InviteFriend(params InviteFriendParams) error {
+
+	label := url.QueryEscape(params.Label)
+
+	query := fmt.Sprintf("%s/api/v2/shared_servers", p.plexTVURL())
+
+	var requestBody inviteFriendBody
+
+	requestBody.MachineIdentifier = params.MachineID
+	requestBody.InvitedEmail = params.UsernameOrEmail
+	requestBody.LibrarySectionIDs = params.LibraryIDs
+
+	settings := inviteFriendSettings{}
+
+	if label != "" {
+		settings.FilterMovies = fmt.Sprintf("label=%s", label)
+		settings.FilterTelevision = fmt.Sprintf("label=%s", label)
+	}
+
+	if params.FilterMovies != "" {
+		settings.FilterMovies = params.FilterMovies
+	}
+
+	if params.FilterTelevision != "" {
+		settings.FilterTelevision = params.FilterTelevision
+	}
+
+	settings.FilterMusic = params.FilterMusic
+	settings.FilterPhotos = params.FilterPhotos
+
+	requestBody.Settings = settings
+
+	jsonBody, jsonErr := json.Marshal(requestBody)
+
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	resp, err := p.post(query, jsonBody, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return errors.New(resp.Status)
+	}
+
+	result := new(SharedServer)
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateFriendAccess limit your friends access to your plex server
+func (p *Plex) UpdateFriendAccess(userID string, params UpdateFriendParams) (bool, error) {
+	// Fix any defaults to statisfy what plex expects
+	if params.AllowSync == "" {
+		params.AllowSync = "0"
+	}
+
+	if params.AllowCameraUpload == "" {
+		params.AllowCameraUpload = "0"
+	}
+
+	if params.AllowChannels == "" {
+		params.AllowChannels = "0"
+	}
+
+	query := fmt.Sprintf("%s/api/friends/%s", p.plexTVURL(), userID)
+
+	parsedQuery, parseErr := url.Parse(query)
+
+	if parseErr != nil {
+		return false, parseErr
+	}
+
+	vals := parsedQuery.Query()
+
+	vals.Add("allowSync", params.AllowSync)
+	vals.Add("allowCameraUpload", params.AllowCameraUpload)
+	vals.Add("allowChannels", params.AllowChannels)
+	vals.Add("filterMovies", params.FilterMovies)
+	vals.Add("filterMusic", params.FilterMusic)
+	vals.Add("filterTelevision", params.FilterTelevision)
+	vals.Add("filterPhotos", params.FilterPhotos)
+
+	parsedQuery.RawQuery = vals.Encode()
+
+	query = parsedQuery.String()
+
+	resp, err := p.put(query, nil, p.Headers)
+
+	if err != nil {
+		return false, err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New(resp.Status)
+	}
+
+	return true, nil
+}
+
+// RemoveFriendAccessToLibrary you can individually revoke access to a library on your server. Such as movies, tv shows, music, etc
+func (p *Plex) RemoveFriendAccessToLibrary(userID, machineID, serverID string) (bool, error) {
+	query := fmt.Sprintf("%s/api/servers/%s/shared_servers/%s", p.plexTVURL(), machineID, serverID)
+
+	resp, err := p.delete(query, p.Headers)
+
+	if err != nil {
+		return false, err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New(resp.Status)
+	}
+
+	return true, nil
+}
+
+// CheckUsernameOrEmail will check if the username is a Plex user or will verify an email is valid
+func (p *Plex) CheckUsernameOrEmail(usernameOrEmail string) (bool, error) {
+
+	usernameOrEmail = url.QueryEscape(usernameOrEmail)
+
+	query := fmt.Sprintf("%s/api/users/validate?invited_email=%s", p.plexTVURL(), usernameOrEmail)
+
+	resp, err := p.post(query, nil, p.Headers)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return false, errors.New(resp.Status)
+	}
+
+	result := new(resultResponse)
+
+	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
+		return false, err
+	}
+
+	return result.Code == 0, nil
+}
+
+// GetClients returns the players connected to the server that can be remote controlled,
+// that is the ones advertising the playback capability
+func (p *Plex) GetClients() ([]Client, error) {
+	var result clientsResponse
+
+	if err := p.getJSON(p.URL+"/clients", &result); err != nil {
+		return nil, err
+	}
+
+	var clients []Client
+
+	for _, client := range result.MediaContainer.Server {
+		if client.HasCapability("playback") {
+			clients = append(clients, client)
+		}
+	}
+
+	return clients, nil
+}
+
+// StopPlayback acts as a remote controller and sends the 'stop' command
+func (p *Plex) StopPlayback(machineID string) error {
+	return p.sendClientCommand(machineID, "/player/playback/stop", nil)
+}
+
+// SetStreams acts as a remote controller and switches the audio or subtitle stream a client is currently
+// playing. Use a streamID of 0 with StreamTypeSubtitle to turn subtitles off. Unlike SetDefaultStream
+// this is not remembered by the server.
+func (p *Plex) SetStreams(machineID string, streamType StreamType, streamID int) error {
+	params := url.Values{}
+	params.Set("type", "video")
+
+	switch streamType {
+	case StreamTypeAudio:
+		params.Set("audioStreamID", strconv.Itoa(streamID))
+	case StreamTypeSubtitle:
+		params.Set("subtitleStreamID", strconv.Itoa(streamID))
+	default:
+		return fmt.Errorf("stream type must be audio (%d) or subtitle (%d)", StreamTypeAudio, StreamTypeSubtitle)
+	}
+
+	return p.sendClientCommand(machineID, "/player/playback/setStreams", params)
+}
+
+// navigation commands of a remote client
+const (
+	NavigationMoveUp      = "moveUp"
+	NavigationMoveDown    = "moveDown"
+	NavigationMoveLeft    = "moveLeft"
+	NavigationMoveRight   = "moveRight"
+	NavigationSelect      = "select"
+	NavigationBack        = "back"
+	NavigationHome        = "home"
+	NavigationContextMenu = "contextMenu"
+	NavigationToggleOSD   = "toggleOSD"
+	NavigationPageUp      = "pageUp"
+	NavigationPageDown    = "pageDown"
+)
+
+// Navigate acts as a remote controller and drives the ui of a client (see GetClients), i.e. NavigationSelect
+func (p *Plex) Navigate(machineID, command string) error {
+	switch command {
+	case NavigationMoveUp, NavigationMoveDown, NavigationMoveLeft, NavigationMoveRight, NavigationSelect,
+		NavigationBack, NavigationHome, NavigationContextMenu, NavigationToggleOSD, NavigationPageUp, NavigationPageDown:
+	default:
+		return fmt.Errorf("invalid navigation command: %s", command)
+	}
+
+	return p.sendClientCommand(machineID, "/player/navigation/"+command, nil)
+}
+
+// commandID numbers the commands sent to remote clients which expect it to increase
+var commandID int64
+
+// sendClientCommand sends a remote control command to the client with machineID through the server.
+// Commands are not retried, as a retried command could run twice on the client.
+func (p *Plex) sendClientCommand(machineID, path string, params url.Values) error {
+	if machineID == "" {
+		return errors.New("a client machine id is required")
+	}
+
+	if params == nil {
+		params = url.Values{}
+	}
+
+	params.Set("commandID", strconv.FormatInt(atomic.AddInt64(&commandID, 1), 10))
+
+	newHeaders := p.Headers
+
+	newHeaders.Accept = "application/xml"
+	newHeaders.TargetClientIdentifier = machineID
+
+	resp, err := p.getOnce(p.URL+path+"?"+params.Encode(), newHeaders)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+
+	return nil
+}
+
+// GetDevices returns every device (servers, players, controllers, etc) registered to your account
+// including their connections and access token
+func (p *Plex) GetDevices() ([]PMSDevices, error) {
+	query := p.plexTVURL() + "/devices.xml"
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return []PMSDevices{}, err
+	}
+
+	defer resp.Body.Close()
+
+	result := new(resourcesResponse)
+
+	if resp.StatusCode != http.StatusOK {
+		return []PMSDevices{}, errors.New(resp.Status)
+	}
+
+	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
+		orNoop(p.Logger).Printf("%v", err)
+
+		return []PMSDevices{}, err
+	}
+
+	return result.Device, nil
+}
+
+// RemoveDevice removes a device from your account, i.e. a stale client registration. See GetDevices for the device id.
+// It returns ErrDeviceNotFound or ErrDeviceNotOwned when the device can not be removed.
+func (p *Plex) RemoveDevice(deviceID int) error {
+	query := fmt.Sprintf("%s/devices/%d.xml", p.plexTVURL(), deviceID)
+
+	resp, err := p.delete(query, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusUnauthorized:
+		return errors.New(ErrorNotAuthorized)
+	case http.StatusForbidden:
+		return ErrDeviceNotOwned
+	case http.StatusNotFound:
+		return ErrDeviceNotFound
+	default:
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+}
+
+// GetServerDevices returns a list of Plex servers with some added info, notably connection data
+func (p *Plex) GetServerDevices() ([]PMSDevices, error) {
+	query := p.plexTVURL() + "/api/resources?includeHttps=1&includeRelay=1&includeIPv6=1"
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return []PMSDevices{}, err
+	}
+
+	defer resp.Body.Close()
+
+	result := new(resourcesResponse)
+
+	if resp.StatusCode != http.StatusOK {
+		return []PMSDevices{}, errors.New(resp.Status)
+	}
+
+	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
+		orNoop(p.Logger).Printf("%v", err)
+
+		return []PMSDevices{}, err
+	}
+
+	return result.Device, nil
+}
+
+// GetServers returns a list of your Plex servers
+func (p *Plex) GetServers() ([]PMSDevices, error) {
+
+	// we can use the https://<pms-ip>/media/providers endpoint
+	// but if the caller does not know the ip beforehand, we can grab it
+	// from plex.tv so we'll use https://plex.tv endpoint to give that option
+
+	devices, err := p.GetServerDevices()
+
+	if err != nil {
+		return devices, err
+	}
+
+	// filter devices for servers
+	var filteredDevices []PMSDevices
+
+	for _, r := range devices {
+		if r.Provides != "server" {
+			continue
+		}
+
+		filteredDevices = append(filteredDevices, r)
+	}
+
+	return filteredDevices, nil
+}
+
+// serverNameTTL is how long ResolveServerName reuses fetched server names, including misses
+var serverNameTTL = 5 * time.Minute
+
+// serverNameCache maps the machine identifiers of servers to their names
+type serverNameCache struct {
+	mu        sync.Mutex
+	names     map[string]string
+	fetchedAt time.Time
+	fetching  *pendingFetch
+}
+
+// ResolveServerName returns the name of the server with machineID among your servers and the servers
+// shared with you. Names are cached by the Plex returned by New and fetched again once they expired, so
+// a new server may take a few minutes to be found. It returns ErrServerNotFound when you can not access
+// the server.
+func (p *Plex) ResolveServerName(machineID string) (string, error) {
+	if machineID == "" {
+		return "", fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	cache := p.serverNames
+
+	if cache == nil {
+		cache = &serverNameCache{}
+	}
+
+	if err := cache.refresh(p); err != nil {
+		return "", err
+	}
+
+	cache.mu.Lock()
+	name, ok := cache.names[machineID]
+	cache.mu.Unlock()
+
+	if !ok {
+		return "", ErrServerNotFound
+	}
+
+	return name, nil
+}
+
+// refresh fetches the server names when the cache expired. Concurrent lookups wait for the same fetch
+// rather than each sending a request.
+func (c *serverNameCache) refresh(p *Plex) error {
+	c.mu.Lock()
+
+	if c.names != nil && time.Since(c.fetchedAt) < serverNameTTL {
+		c.mu.Unlock()
+		return nil
+	}
+
+	if f := c.fetching; f != nil {
+		c.mu.Unlock()
+		<-f.done
+
+		return f.err
+	}
+
+	f := &pendingFetch{done: make(chan struct{})}
+	c.fetching = f
+	c.mu.Unlock()
+
+	servers, err := p.GetServers()
+
+	c.mu.Lock()
+
+	if err == nil {
+		c.names = make(map[string]string, len(servers))
+
+		for _, server := range servers {
+			c.names[server.ClientIdentifier] = server.Name
+		}
+
+		c.fetchedAt = time.Now()
+	}
+
+	c.fetching = nil
+	c.mu.Unlock()
+
+	f.err = err
+	close(f.done)
+
+	return err
+}
+
+// GetServersInfo returns info about all of your Plex servers
+func (p *Plex) GetServersInfo() (ServerInfo, error) {
+	query := p.plexTVURL() + "/api/servers"
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return ServerInfo{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ServerInfo{}, errors.New(resp.Status)
+	}
+
+	result := ServerInfo{}
+
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		orNoop(p.Logger).Printf("%v", err)
+
+		return ServerInfo{}, err
+	}
+
+	return result, nil
+}
+
+// GetMachineID returns the machine id of the server with the associated access token
+func (p *Plex) GetMachineID() (string, error) {
+	if p.Token == "" {
+		return "", errors.New("a token is required to fetch machine id")
+	}
+
+	servers, err := p.GetServersInfo()
+
+	if err != nil {
+		return "", err
+	}
+
+	var machineID string
+
+	for _, server := range servers.Server {
+		if server.AccessToken == p.Token {
+			machineID = server.MachineIdentifier
+		}
+	}
+
+	if machineID == "" {
+		return "", errors.New("could not fetch machine id")
+	}
+
+	return machineID, nil
+}
+
+// GetSections of your plex server. This is useful when inviting a user
+// as you can restrict the invited user to a library (i.e. Movie's, TV Shows)
+func (p *Plex) GetSections(machineID string) ([]ServerSections, error) {
+	query := fmt.Sprintf("%s/api/servers/%s", p.plexTVURL(), machineID)
+
+	newHeaders := p.Headers
+
+	newHeaders.Accept = "application/xml"
+
+	resp, err := p.get(query, newHeaders)
+
+	if err != nil {
+		return []ServerSections{}, err
+	}
+
+	defer resp.Body.Close()
+
+	var result SectionIDResponse
+
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		orNoop(p.Logger).Printf("%v", err)
+
+		return []ServerSections{}, err
+	}
+
+	// Look for our server via the machine id
+	for _, server := range result.Server {
+		if server.MachineIdentifier != machineID {
+			continue
+		}
+
+		return server.Section, nil
+	}
+
+	return []ServerSections{}, nil
+}
+
+// GetLibraries of your Plex server. My ideal use-case would be
+// to get library count to determine label index
+func (p *Plex) GetLibraries() (LibrarySections, error) {
+	query := fmt.Sprintf("%s/library/sections", p.URL)
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return LibrarySections{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return LibrarySections{}, errors.New(resp.Status)
+	}
+
+	var result LibrarySections
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		orNoop(p.Logger).Printf("%v", err)
+
+		return LibrarySections{}, err
+	}
+
+	return result, nil
+}
+
+// GetSectionByTitle returns the library section whose title matches title regardless of case.
+// It returns ErrSectionNotFound when there is none.
+func (p *Plex) GetSectionByTitle(title string) (*Directory, error) {
+	if title == "" {
+		return nil, fmt.Errorf(ErrorCommon, ErrorTitleRequired)
+	}
+
+	libraries, err := p.GetLibraries()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, section := range libraries.MediaContainer.Directory {
+		if strings.EqualFold(section.Title, title) {
+			return &section, nil
+		}
+	}
+
+	return nil, ErrSectionNotFound
+}
+
+// GetLibraryContent retrieve the content inside a library
+func (p *Plex) GetLibraryContent(sectionKey string, filter string) (SearchResults, error) {
+	query := fmt.Sprintf("%s/library/sections/%s/all%s", p.URL, sectionKey, filter)
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return SearchResults{}, err
+	}
+
+	if resp.Status == ErrorInvalidToken {
+		return SearchResults{}, errors.New("invalid token")
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return SearchResults{}, errors.New(ErrorNotAuthorized)
+	}
+
+	if resp.StatusCode == http.StatusBadRequest {
+		return SearchResults{}, errors.New("there was an error in the request")
+	}
+
+	defer resp.Body.Close()
+
+	var results SearchResults
+
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return SearchResults{}, err
+	}
+
+	return results, nil
+}
+
+// GetLibraryContentPage returns size items of a library section starting at start. The result's
+// TotalSize is the number of items in the section so pages can be fetched until Offset+Size reaches it.
+func (p *Plex) GetLibraryContentPage(sectionKey, filter string, start, size int) (SearchResults, error) {
+	if sectionKey == "" {
+		return SearchResults{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	h := p.Headers
+	h.ContainerStart = strconv.Itoa(start)
+	h.ContainerSize = strconv.Itoa(size)
+
+	resp, err := p.get(fmt.Sprintf("%s/library/sections/%s/all%s", p.URL, sectionKey, filter), h)
+
+	if err != nil {
+		return SearchResults{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return SearchResults{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return SearchResults{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	var results SearchResults
+
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return SearchResults{}, err
+	}
+
+	return results, nil
+}
+
+// GetSectionSize returns the number of items in a library section without fetching any of them
+func (p *Plex) GetSectionSize(sectionKey string) (int, error) {
+	results, err := p.GetLibraryContentPage(sectionKey, "", 0, 0)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return results.MediaContainer.TotalSize, nil
+}
+
+// QueryLibrary returns the content of a library section matching a LibraryQuery
+func (p *Plex) QueryLibrary(sectionKey string, query LibraryQuery) (SearchResults, error) {
+	if sectionKey == "" {
+		return SearchResults{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	if err := query.Validate(); err != nil {
+		return SearchResults{}, err
+	}
+
+	return p.GetLibraryContent(sectionKey, query.String())
+}
+
+// GetSectionAll returns the content of a library section sorted by sort, i.e. addedAt:desc,
+// titleSort:asc or rating:desc. Several sorts are separated by commas
+func (p *Plex) GetSectionAll(sectionKey, sort string) (SearchResults, error) {
+	if sort == "" {
+		return SearchResults{}, errors.New("a sort is required")
+	}
+
+	return p.QueryLibrary(sectionKey, LibraryQuery{Sort: sort})
+}
+
+// GetFilters returns the fields a library section can be filtered by (i.e. genre, year, decade,
+// contentRating and resolution) along with their possible values
+func (p *Plex) GetFilters(sectionKey string) ([]LibrarySectionFilter, error) {
+	if sectionKey == "" {
+		return nil, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	var filters libraryFiltersResponse
+
+	if err := p.getJSON(fmt.Sprintf("%s/library/sections/%s/filters", p.URL, sectionKey), &filters); err != nil {
+		return nil, err
+	}
+
+	result := filters.MediaContainer.Directory
+
+	for ii, filter := range result {
+		if filter.FilterType == "boolean" || filter.Key == "" {
+			continue
+		}
+
+		var values filterValuesResponse
+
+		if err := p.getJSON(p.URL+filter.Key, &values); err != nil {
+			return nil, err
+		}
+
+		result[ii].Values = values.MediaContainer.Directory
+	}
+
+	return result, nil
+}
+
+// getJSON requests query from the server and decodes the json response into v
+func (p *Plex) getJSON(query string, v interface{}) error {
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// GetAgents returns the metadata agents of the server that support mediaType (i.e. movie or 1),
+// or every agent when mediaType is empty
+func (p *Plex) GetAgents(mediaType string) ([]Agent, error) {
+	query := fmt.Sprintf("%s/system/agents", p.URL)
+
+	if mediaType != "" {
+		query += "?mediaType=" + GetMediaTypeID(mediaType)
+	}
+
+	var result agentsResponse
+
+	if err := p.getJSON(query, &result); err != nil {
+		return nil, err
+	}
+
+	return result.MediaContainer.Agent, nil
+}
+
+// GetScanners returns the library scanners of the server for mediaType (i.e. movie or 1)
+func (p *Plex) GetScanners(mediaType string) ([]Scanner, error) {
+	if mediaType == "" {
+		return nil, errors.New("a media type is required")
+	}
+
+	var result scannersResponse
+
+	if err := p.getJSON(fmt.Sprintf("%s/system/scanners/%s", p.URL, GetMediaTypeID(mediaType)), &result); err != nil {
+		return nil, err
+	}
+
+	return result.MediaContainer.Scanner, nil
+}
+
+// CreateLibrary will create a new library on your Plex server
+func (p *Plex) CreateLibrary(params CreateLibraryParams) error {
+	// all params are required
+	if params.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if params.Location == "" {
+		return errors.New("location is required")
+	}
+
+	if params.LibraryType == "" {
+		return errors.New("libraryType is required")
+	}
+
+	if params.Agent == "" {
+		return errors.New("agent is required")
+	}
+
+	if params.Scanner == "" {
+		return errors.New("scanner is required")
+	}
+
+	if params.Language == "" {
+		params.Language = "en"
+	}
+
+	query := p.URL + "/library/sections"
+
+	parsedQuery, err := url.Parse(query)
+
+	if err != nil {
+		return err
+	}
+
+	queryValues := parsedQuery.Query()
+
+	queryValues.Add("name", params.Name)
+	queryValues.Add("location", params.Location)
+	queryValues.Add("language", params.Language)
+	queryValues.Add("type", params.LibraryType)
+	queryValues.Add("agent", params.Agent)
+	queryValues.Add("scanner", params.Scanner)
+
+	parsedQuery.RawQuery = queryValues.Encode()
+
+	query = parsedQuery.String()
+
+	resp, err := p.post(query, nil, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return errors.New(resp.Status)
+	}
+
+	return nil
+}
+
+// UpdateLibrary changes the settings of a library. Only the fields of params that are set are sent,
+// the others are left unchanged. LibraryType can not be changed and is ignored.
+// Setting Location replaces the folders of the library, see AddLibraryLocation to add one.
+func (p *Plex) UpdateLibrary(sectionKey string, params CreateLibraryParams) error {
+	if sectionKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	values := url.Values{}
+
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+
+	set("name", params.Name)
+	set("location", params.Location)
+	set("language", params.Language)
+	set("agent", params.Agent)
+	set("scanner", params.Scanner)
+
+	if len(values) == 0 {
+		return errors.New("nothing to update")
+	}
+
+	return p.editLibrary(sectionKey, values)
+}
+
+// AddLibraryLocation adds a folder to a library. path must be visible to the server.
+func (p *Plex) AddLibraryLocation(sectionKey, path string) error {
+	if path == "" {
+		return errors.New("location is required")
+	}
+
+	section, err := p.getSection(sectionKey)
+
+	if err != nil {
+		return err
+	}
+
+	values := url.Values{}
+	values.Set("agent", section.Agent)
+
+	for _, location := range section.Location {
+		if location.Path == path {
+			return nil
+		}
+
+		values.Add("location", location.Path)
+	}
+
+	values.Add("location", path)
+
+	if err := p.editLibrary(sectionKey, values); err != nil {
+		return fmt.Errorf("failed to add location %s: %v", path, err)
+	}
+
+	// plex ignores folders it can not access
+	section, err = p.getSection(sectionKey)
+
+	if err != nil {
+		return err
+	}
+
+	for _, location := range section.Location {
+		if location.Path == path {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("plex could not add location %s, make sure the server can access it", path)
+}
+
+// RemoveLibraryLocation removes a folder from a library. The last folder of a library can not be removed.
+func (p *Plex) RemoveLibraryLocation(sectionKey, path string) error {
+	section, err := p.getSection(sectionKey)
+
+	if err != nil {
+		return err
+	}
+
+	values := url.Values{}
+	values.Set("agent", section.Agent)
+
+	found := false
+
+	for _, location := range section.Location {
+		if location.Path == path {
+			found = true
+			continue
+		}
+
+		values.Add("location", location.Path)
+	}
+
+	if !found {
+		return fmt.Errorf("%s is not a location of library %s", path, sectionKey)
+	}
+
+	if len(values["location"]) == 0 {
+		return errors.New("can not remove the last location of a library")
+	}
+
+	return p.editLibrary(sectionKey, values)
+}
+
+// IsLibraryRefreshing reports whether a library section is being scanned
+func (p *Plex) IsLibraryRefreshing(sectionKey string) (bool, error) {
+	section, err := p.getSection(sectionKey)
+
+	if err != nil {
+		return false, err
+	}
+
+	return section.Refreshing, nil
+}
+
+// scanPollInterval is how often WaitForScan checks whether the scan started or finished
+var scanPollInterval = 2 * time.Second
+
+// scanStartTimeout is how long WaitForScan waits for a requested scan to start
+var scanStartTimeout = 10 * time.Second
+
+// WaitForScan blocks until the library section is no longer being scanned or ctx is done. The server
+// may take a moment to start a scan requested with ScanLibrary, so WaitForScan first waits up to 10
+// seconds for the scan to start. It returns nil when the scan did not start in that time, i.e. when it
+// finished before the first poll.
+func (p *Plex) WaitForScan(ctx context.Context, sectionKey string) error {
+	ticker := time.NewTicker(scanPollInterval)
+	defer ticker.Stop()
+
+	started := false
+	startDeadline := time.Now().Add(scanStartTimeout)
+
+	for {
+		refreshing, err := p.WithContext(ctx).IsLibraryRefreshing(sectionKey)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if refreshing {
+			started = true
+		} else if started || time.Now().After(startDeadline) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// getSection returns the library section with sectionKey
+func (p *Plex) getSection(sectionKey string) (Directory, error) {
+	if sectionKey == "" {
+		return Directory{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	libraries, err := p.GetLibraries()
+
+	if err != nil {
+		return Directory{}, err
+	}
+
+	for _, section := range libraries.MediaContainer.Directory {
+		if section.Key == sectionKey {
+			return section, nil
+		}
+	}
+
+	return Directory{}, ErrSectionNotFound
+}
+
+// editLibrary sends the edit-section request of a library with values
+func (p *Plex) editLibrary(sectionKey string, values url.Values) error {
+	query := fmt.Sprintf("%s/library/sections/%s?%s", p.URL, sectionKey, values.Encode())
+
+	resp, err := p.put(query, nil, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ScanLibrary scans the library from your Plex server via library key (or id)
+func (p *Plex) ScanLibrary(key string) error {
+	query := fmt.Sprintf("%s/library/sections/%s/refresh", p.URL, key)
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+
+	return nil
+}
+
+// DeleteLibrary removes the library from your Plex server via library key (or id)
+func (p *Plex) DeleteLibrary(key string) error {
+	query := fmt.Sprintf("%s/library/sections/%s", p.URL, key)
+
+	resp, err := p.delete(query, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+
+	return nil
+}
+
+// GetLibraryLabels of your plex server
+func (p *Plex) GetLibraryLabels(sectionKey, sectionIndex string) (LibraryLabels, error) {
+
+	if sectionIndex == "" {
+		sectionIndex = "1"
+	}
+
+	query := fmt.Sprintf("%s/library/sections/%s/labels?type=%s", p.URL, sectionKey, sectionIndex)
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return LibraryLabels{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return LibraryLabels{}, errors.New(resp.Status)
+	}
+
+	var result LibraryLabels
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		orNoop(p.Logger).Printf("%v", err)
+
+		return LibraryLabels{}, err
+	}
+
+	return result, nil
+}
+
+// AddLabelToMedia restrict access to certain media. Requires a Plex Pass.
+// mediaType is the media type (1), id is the ratingKey or media id, label is your label, locked is unknown
+// 1. A reference to the plex media types: https://github.com/Arcanemagus/plex-api/wiki/MediaTypes
+// XXX: Currently plex is capitalizing the first letter
+func (p *Plex) AddLabelToMedia(mediaType, sectionID, id, label, locked string) (bool, error) {
+
+	query := fmt.Sprintf("%s/library/sections/%s/all", p.URL, sectionID)
+
+	parsedQuery, parseErr := url.Parse(query)
+
+	if parseErr != nil {
+		return false, parseErr
+	}
+
+	vals := parsedQuery.Query()
+
+	vals.Add("type", mediaType)
+	vals.Add("id", id)
+	vals.Add("label[0].tag.tag", label)
+	// vals.Add("label.locked", locked)
+
+	parsedQuery.RawQuery = vals.Encode()
+
+	query = parsedQuery.String()
+
+	resp, err := p.put(query, nil, p.Headers)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// RemoveLabelFromMedia to remove a label from a piece of media Requires a Plex Pass.
+func (p *Plex) RemoveLabelFromMedia(mediaType, sectionID, id, label, locked string) (bool, error) {
+
+	query := fmt.Sprintf("%s/library/sections/%s/all", p.URL, sectionID)
+
+	parsedQuery, parseErr := url.Parse(query)
+
+	if parseErr != nil {
+		return false, parseErr
+	}
+
+	vals := parsedQuery.Query()
+
+	vals.Add("type", mediaType)
+	vals.Add("id", id)
+	vals.Add("label[].tag.tag-", label)
+	vals.Add("label.locked", locked)
+
+	parsedQuery.RawQuery = vals.Encode()
+
+	query = parsedQuery.String()
+
+	resp, err := p.put(query, nil, p.Headers)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// GetCollections returns the collections inside of a library section
+func (p *Plex) GetCollections(sectionKey string) (Collections, error) {
+	if sectionKey == "" {
+		return Collections{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	query := fmt.Sprintf("%s/library/sections/%s/collections", p.URL, sectionKey)
+
+	resp, err := p.get(query, p.Headers)
+
+	if err != nil {
+		return Collections{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return Collections{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return Collections{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	var result Collections
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Collections{}, err
+	}
+
+	return result, nil
+}
+
+// CreateCollection creates a new collection in a library section containing the media of ratingKeys
+func (p *Plex) CreateCollection(sectionKey, title string, ratingKeys []int) (Collection, error) {
+	if sectionKey == "" {
+		return Collection{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	if title == "" {
+		return Collection{}, fmt.Errorf(ErrorCommon, ErrorTitleRequired)
+	}
+
+	if len(ratingKeys) == 0 {
+		return Collection{}, errors.New("at least one rating key is required")
+	}
+
+	libraries, err := p.GetLibraries()
+
+	if err != nil {
+		return Collection{}, err
+	}
+
+	var sectionType string
+
+	for _, library := range libraries.MediaContainer.Directory {
+		if library.Key == sectionKey {
+			sectionType = library.Type
+			break
+		}
+	}
+
+	if sectionType == "" {
+		return Collection{}, fmt.Errorf("could not find library section %s", sectionKey)
+	}
+
+	uri, err := p.libraryURI(ratingKeys)
+
+	if err != nil {
+		return Collection{}, err
+	}
+
+	parsedQuery, err := url.Parse(p.URL + "/library/collections")
+
+	if err != nil {
+		return Collection{}, err
+	}
+
+	vals := parsedQuery.Query()
+
+	vals.Add("type", GetMediaTypeID(sectionType))
+	vals.Add("title", title)
+	vals.Add("smart", "0")
+	vals.Add("sectionId", sectionKey)
+	vals.Add("uri", uri)
+
+	parsedQuery.RawQuery = vals.Encode()
+
+	resp, err := p.post(parsedQuery.String(), nil, p.Headers)
+
+	if err != nil {
+		return Collection{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return Collection{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return Collection{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	var result Collections
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Collection{}, err
+	}
+
+	if len(result.MediaContainer.Metadata) == 0 {
+		return Collection{}, errors.New("server did not return the created collection")
+	}
+
+	return result.MediaContainer.Metadata[0], nil
+}
+
+// AddToCollection adds media to an existing collection via their rating keys
+func (p *Plex) AddToCollection(collectionKey string, ratingKeys []int) error {
+	if collectionKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	if len(ratingKeys) == 0 {
+		return errors.New("at least one rating key is required")
+	}
+
+	uri, err := p.libraryURI(ratingKeys)
+
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("%s/library/collections/%s/items?uri=%s", p.URL, collectionKey, url.QueryEscape(uri))
+
+	resp, err := p.put(query, nil, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// RemoveFromCollection removes a single piece of media from a collection via its rating key
+func (p *Plex) RemoveFromCollection(collectionKey string, ratingKey int) error {
+	if collectionKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	query := fmt.Sprintf("%s/library/collections/%s/items/%d", p.URL, collectionKey, ratingKey)
+
+	resp, err := p.delete(query, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// SetCollectionPrefs changes the advanced settings of a collection. mode is one of the CollectionMode
+// constants and sort is one of the CollectionSort constants. Pass an empty string to leave a setting unchanged
+func (p *Plex) SetCollectionPrefs(collectionKey, mode, sort string) error {
+	if collectionKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	switch mode {
+	case "", CollectionModeDefault, CollectionModeHide, CollectionModeHideItems, CollectionModeShowItems:
+	default:
+		return fmt.Errorf("invalid collection mode: %s", mode)
+	}
+
+	switch sort {
+	case "", CollectionSortRelease, CollectionSortAlphabetical:
+	default:
+		return fmt.Errorf("invalid collection sort: %s", sort)
+	}
+
+	parsedQuery, err := url.Parse(fmt.Sprintf("%s/library/metadata/%s/prefs", p.URL, collectionKey))
+
+	if err != nil {
+		return err
+	}
+
+	vals := parsedQuery.Query()
+
+	if mode != "" {
+		vals.Add("collectionMode", mode)
+	}
+
+	if sort != "" {
+		vals.Add("collectionSort", sort)
+	}
+
+	parsedQuery.RawQuery = vals.Encode()
+
+	resp, err := p.put(parsedQuery.String(), nil, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// EditMetadata changes metadata fields (title, titleSort, summary, year, contentRating, etc) of a piece of media.
+// mediaType can be the name (movie) or the id (1) of the media type. A locked field will not be
+// overwritten by the metadata agent on the next refresh
+func (p *Plex) EditMetadata(ratingKey, sectionID, mediaType string, fields map[string]MetadataEdit) error {
+	if ratingKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	if len(fields) == 0 {
+		return errors.New("at least one field is required")
+	}
+
+	parsedQuery, err := url.Parse(fmt.Sprintf("%s/library/sections/%s/all", p.URL, sectionID))
+
+	if err != nil {
+		return err
+	}
+
+	vals := parsedQuery.Query()
+
+	vals.Add("type", GetMediaTypeID(mediaType))
+	vals.Add("id", ratingKey)
+
+	for field, edit := range fields {
+		locked := "0"
+
+		if edit.Locked {
+			locked = "1"
+		}
+
+		vals.Add(field+".value", edit.Value)
+		vals.Add(field+".locked", locked)
+	}
+
+	parsedQuery.RawQuery = vals.Encode()
+
+	resp, err := p.put(parsedQuery.String(), nil, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// AddTag adds a tag to a piece of media. tagType is one of genre, collection, label, mood or style
+func (p *Plex) AddTag(ratingKey, sectionID, mediaType, tagType, tag string) error {
+	return p.editTag(ratingKey, sectionID, mediaType, tagType+"[0].tag.tag", tagType, tag)
+}
+
+// RemoveTag removes a tag from a piece of media. tagType is one of genre, collection, label, mood or style
+func (p *Plex) RemoveTag(ratingKey, sectionID, mediaType, tagType, tag string) error {
+	return p.editTag(ratingKey, sectionID, mediaType, tagType+"[].tag.tag-", tagType, tag)
+}
+
+func (p *Plex) editTag(ratingKey, sectionID, mediaType, param, tagType, tag string) error {
+	switch tagType {
+	case "genre", "collection", "label", "mood", "style":
+	default:
+		return fmt.Errorf("invalid tag type: %s", tagType)
+	}
+
+	if ratingKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	if tag == "" {
+		return errors.New("a tag is required")
+	}
+
+	parsedQuery, err := url.Parse(fmt.Sprintf("%s/library/sections/%s/all", p.URL, sectionID))
+
+	if err != nil {
+		return err
+	}
+
+	vals := parsedQuery.Query()
+
+	vals.Add("type", GetMediaTypeID(mediaType))
+	vals.Add("id", ratingKey)
+	vals.Add(param, tag)
+
+	parsedQuery.RawQuery = vals.Encode()
+
+	resp, err := p.put(parsedQuery.String(), nil, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// GetExtras returns the extras of a piece of media, such as trailers, featurettes and deleted scenes.
+// The ExtraType and Subtype of each extra tell which kind of extra it is
+func (p *Plex) GetExtras(ratingKey string) (MediaMetadata, error) {
+	if ratingKey == "" {
+		return MediaMetadata{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	var results MediaMetadata
+
+	if err := p.getJSON(fmt.Sprintf("%s/library/metadata/%s/extras", p.URL, ratingKey), &results); err != nil {
+		return MediaMetadata{}, err
+	}
+
+	return results, nil
+}
+
+// GetMatches returns the candidate matches of the metadata agent for a piece of media, best match first.
+// Use FixMatch with the GUID of the right candidate to correct a mismatched item
+func (p *Plex) GetMatches(ratingKey string) ([]MetadataMatch, error) {
+	if ratingKey == "" {
+		return nil, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	var results metadataMatchesResponse
+
+	if err := p.getJSON(fmt.Sprintf("%s/library/metadata/%s/matches?manual=1", p.URL, ratingKey), &results); err != nil {
+		return nil, err
+	}
+
+	return results.MediaContainer.SearchResult, nil
+}
+
+// FixMatch matches a piece of media to matchGUID, a GUID returned by GetMatches. name is the
+// candidate's name and is optional
+func (p *Plex) FixMatch(ratingKey, matchGUID, name string) error {
+	if ratingKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	if matchGUID == "" {
+		return errors.New("a match guid is required")
+	}
+
+	vals := url.Values{}
+	vals.Set("guid", matchGUID)
+
+	if name != "" {
+		vals.Set("name", name)
+	}
+
+	return p.putMetadata(fmt.Sprintf("%s/library/metadata/%s/match?%s", p.URL, ratingKey, vals.Encode()))
+}
+
+// UnmatchMetadata clears the metadata agent match of a piece of media, i.e. to reset it before matching it again with FixMatch
+func (p *Plex) UnmatchMetadata(ratingKey string) error {
+	if ratingKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	return p.putMetadata(fmt.Sprintf("%s/library/metadata/%s/unmatch", p.URL, ratingKey))
+}
+
+// MergeItems merges otherRatingKeys into primaryRatingKey, i.e. when the same movie was added as several items.
+// The merged item keeps the metadata of primaryRatingKey and the media of every item
+func (p *Plex) MergeItems(primaryRatingKey string, otherRatingKeys []string) error {
+	if primaryRatingKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	if len(otherRatingKeys) == 0 {
+		return errors.New("at least one rating key to merge is required")
+	}
+
+	for _, key := range otherRatingKeys {
+		if key == "" || key == primaryRatingKey {
+			return fmt.Errorf("invalid rating key to merge: %q", key)
+		}
+	}
+
+	vals := url.Values{}
+	vals.Set("ids", strings.Join(otherRatingKeys, ","))
+
+	return p.putMetadata(fmt.Sprintf("%s/library/metadata/%s/merge?%s", p.URL, primaryRatingKey, vals.Encode()))
+}
+
+// SplitItem splits a merged item back into an item for each of its media
+func (p *Plex) SplitItem(ratingKey string) error {
+	if ratingKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	return p.putMetadata(fmt.Sprintf("%s/library/metadata/%s/split", p.URL, ratingKey))
+}
+
+// GetPosters returns the posters available for a piece of media
+func (p *Plex) GetPosters(ratingKey string) ([]Artwork, error) {
+	return p.getArtwork(ratingKey, "posters")
+}
+
+// SetPoster selects the poster of a piece of media, posterKey being the RatingKey of one of its posters
+func (p *Plex) SetPoster(ratingKey, posterKey string) error {
+	return p.setArtwork(ratingKey, "poster", posterKey)
+}
+
+// GetArts returns the arts (backgrounds) available for a piece of media
+func (p *Plex) GetArts(ratingKey string) ([]Artwork, error) {
+	return p.getArtwork(ratingKey, "arts")
+}
+
+// SetArt selects the art (background) of a piece of media, artKey being the RatingKey of one of its arts
+func (p *Plex) SetArt(ratingKey, artKey string) error {
+	return p.setArtwork(ratingKey, "art", artKey)
+}
+
+// UploadPoster uploads the image read from r as a poster of a piece of media and returns the RatingKey of the new poster,
+// which the server selects. The content type of the image is detected from its content
+func (p *Plex) UploadPoster(ratingKey string, r io.Reader) (string, error) {
+	if ratingKey == "" {
+		return "", fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	image, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(image) == 0 {
+		return "", errors.New("poster image is empty")
+	}
+
+	contentType := http.DetectContentType(image)
+
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", fmt.Errorf("poster is not an image: %s", contentType)
+	}
+
+	return p.uploadPoster(fmt.Sprintf("%s/library/metadata/%s/posters", p.URL, ratingKey), ratingKey, image, contentType)
+}
+
+// UploadPosterFromURL has the server download a poster of a piece of media from posterURL and returns the RatingKey
+// of the new poster, which the server selects
+func (p *Plex) UploadPosterFromURL(ratingKey, posterURL string) (string, error) {
+	if ratingKey == "" {
+		return "", fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	if posterURL == "" {
+		return "", errors.New("a poster url is required")
+	}
+
+	vals := url.Values{}
+	vals.Set("url", posterURL)
+
+	// the server downloads the poster, so the request has no body nor content type
+	return p.uploadPoster(fmt.Sprintf("%s/library/metadata/%s/posters?%s", p.URL, ratingKey, vals.Encode()), ratingKey, nil, "")
+}
+
+// uploadPoster posts a poster to query and returns the RatingKey of the poster the upload added
+func (p *Plex) uploadPoster(query, ratingKey string, body []byte, contentType string) (string, error) {
+	// the server does not reply with the new poster, which is found by comparing the posters
+	// before and after the upload
+	before, err := p.GetPosters(ratingKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	existing := make(map[string]bool, len(before))
+
+	for _, poster := range before {
+		existing[poster.RatingKey] = true
+	}
+
+	newHeaders := p.Headers
+	newHeaders.ContentType = contentType
+
+	resp, err := p.post(query, body, newHeaders)
+
+	if err != nil {
+		return "", err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	after, err := p.GetPosters(ratingKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	var added []Artwork
 
-	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
-		fmt.Println(err.Error())
+	for _, poster := range after {
+		if !existing[poster.RatingKey] {
+			added = append(added, poster)
+		}
+	}
 
-		return []ServerSections{}, err
+	if len(added) == 0 {
+		return "", errors.New("uploaded poster not found")
 	}
 
-	// Look for our server via the machine id
-	for _, server := range result.Server {
-		if server.MachineIdentifier != machineID {
-			continue
+	// prefer the poster the server selected, should the upload have added several
+	for _, poster := range added {
+		if poster.Selected {
+			return poster.RatingKey, nil
 		}
-
-		return server.Section, nil
 	}
 
-	return []ServerSections{}, nil
+	return added[0].RatingKey, nil
 }
 
-// GetLibraries of your Plex server. My ideal use-case would be
-// to get library count to determine label index
-func (p *Plex) GetLibraries() (LibrarySections, error) {
-	query := fmt.Sprintf("%s/library/sections", p.URL)
+// getArtwork returns the posters or arts (kind) of a piece of media
+func (p *Plex) getArtwork(ratingKey, kind string) ([]Artwork, error) {
+	if ratingKey == "" {
+		return nil, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
 
-	resp, err := p.get(query, p.Headers)
+	var results artworkResponse
 
-	if err != nil {
-		return LibrarySections{}, err
+	if err := p.getJSON(fmt.Sprintf("%s/library/metadata/%s/%s", p.URL, ratingKey, kind), &results); err != nil {
+		return nil, err
 	}
 
-	defer resp.Body.Close()
+	return results.MediaContainer.Metadata, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return LibrarySections{}, errors.New(resp.Status)
+// setArtwork selects the poster or art (kind) of a piece of media
+func (p *Plex) setArtwork(ratingKey, kind, artworkKey string) error {
+	if ratingKey == "" || artworkKey == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
 	}
 
-	var result LibrarySections
+	vals := url.Values{}
+	vals.Set("url", artworkKey)
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		fmt.Println(err.Error())
+	return p.putMetadata(fmt.Sprintf("%s/library/metadata/%s/%s?%s", p.URL, ratingKey, kind, vals.Encode()))
+}
 
-		return LibrarySections{}, err
+// putMetadata sends a PUT request editing a piece of media to query
+func (p *Plex) putMetadata(query string) error {
+	resp, err := p.put(query, nil, p.Headers)
+
+	if err != nil {
+		return err
 	}
 
-	return result, nil
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	return nil
 }
 
-// GetLibraryContent retrieve the content inside a library
-func (p *Plex) GetLibraryContent(sectionKey string, filter string) (SearchResults, error) {
-	query := fmt.Sprintf("%s/library/sections/%s/all%s", p.URL, sectionKey, filter)
+// GetSettings returns the preferences of your Plex server
+func (p *Plex) GetSettings() ([]Setting, error) {
+	query := fmt.Sprintf("%s/:/prefs", p.URL)
 
 	resp, err := p.get(query, p.Headers)
 
 	if err != nil {
-		return SearchResults{}, err
+		return []Setting{}, err
 	}
 
-	if resp.Status == ErrorInvalidToken {
-		return SearchResults{}, errors.New("invalid token")
-	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return SearchResults{}, errors.New(ErrorNotAuthorized)
-	}
-
-	if resp.StatusCode == http.StatusBadRequest {
-		return SearchResults{}, errors.New("there was an error in the request")
+		return []Setting{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return []Setting{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
-	defer resp.Body.Close()
-
-	var results SearchResults
+	var result settingsResponse
 
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		return SearchResults{}, err
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return []Setting{}, err
 	}
 
-	return results, nil
+	return result.MediaContainer.Setting, nil
 }
 
-// CreateLibrary will create a new library on your Plex server
-func (p *Plex) CreateLibrary(params CreateLibraryParams) error {
-	// all params are required
-	if params.Name == "" {
-		return errors.New("name is required")
-	}
+// SetSetting changes a preference of your Plex server (i.e. TranscoderQuality or ButlerStartHour).
+// The setting must exist on the server and, for enumerated settings, value must be one of its options
+func (p *Plex) SetSetting(id, value string) error {
+	settings, err := p.GetSettings()
 
-	if params.Location == "" {
-		return errors.New("location is required")
+	if err != nil {
+		return err
 	}
 
-	if params.LibraryType == "" {
-		return errors.New("libraryType is required")
-	}
+	var setting *Setting
 
-	if params.Agent == "" {
-		return errors.New("agent is required")
+	for ii := range settings {
+		if settings[ii].ID == id {
+			setting = &settings[ii]
+			break
+		}
 	}
 
-	if params.Scanner == "" {
-		return errors.New("scanner is required")
+	if setting == nil {
+		return fmt.Errorf("unknown setting: %s", id)
 	}
 
-	if params.Language == "" {
-		params.Language = "en"
+	if options := setting.Options(); options != nil {
+		isValid := false
+
+		for _, option := range options {
+			if option.Value == value {
+				isValid = true
+				break
+			}
+		}
+
+		if !isValid {
+			return fmt.Errorf("invalid value for setting %s: %s", id, value)
+		}
 	}
 
-	query := p.URL + "/library/sections"
+	query := fmt.Sprintf("%s/:/prefs?%s=%s", p.URL, url.QueryEscape(id), url.QueryEscape(value))
 
-	parsedQuery, err := url.Parse(query)
+	resp, err := p.put(query, nil, p.Headers)
 
 	if err != nil {
 		return err
 	}
 
-	queryValues := parsedQuery.Query()
+	defer resp.Body.Close()
 
-	queryValues.Add("name", params.Name)
-	queryValues.Add("location", params.Location)
-	queryValues.Add("language", params.Language)
-	queryValues.Add("type", params.LibraryType)
-	queryValues.Add("agent", params.Agent)
-	queryValues.Add("scanner", params.Scanner)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server rejected value for setting %s: %s", id, resp.Status)
+	}
 
-	parsedQuery.RawQuery = queryValues.Encode()
+	return nil
+}
 
-	query = parsedQuery.String()
+// remoteAccessSetting is the setting publishing the server to plex.tv for remote access
+const remoteAccessSetting = "PublishServerOnPlexOnlineKey"
 
-	resp, err := p.post(query, nil, p.Headers)
+// GetRemoteAccessState returns whether the server is published for remote access and the state of its port mapping
+func (p *Plex) GetRemoteAccessState() (RemoteAccessState, error) {
+	var account myPlexAccountResponse
+
+	if err := p.getJSON(fmt.Sprintf("%s/myplex/account", p.URL), &account); err != nil {
+		return RemoteAccessState{}, err
+	}
+
+	settings, err := p.GetSettings()
 
 	if err != nil {
-		return err
+		return RemoteAccessState{}, err
 	}
 
-	defer resp.Body.Close()
+	state := RemoteAccessState{
+		MappingState:   account.MyPlex.MappingState,
+		MappingError:   account.MyPlex.MappingError,
+		SignInState:    account.MyPlex.SignInState,
+		PublicAddress:  account.MyPlex.PublicAddress,
+		PublicPort:     int(account.MyPlex.PublicPort),
+		PrivateAddress: account.MyPlex.PrivateAddress,
+		PrivatePort:    int(account.MyPlex.PrivatePort),
+	}
 
-	if resp.StatusCode != http.StatusCreated {
-		return errors.New(resp.Status)
+	for _, setting := range settings {
+		if setting.ID == remoteAccessSetting {
+			state.Published, _ = strconv.ParseBool(setting.Value)
+			break
+		}
 	}
 
-	return nil
+	return state, nil
 }
 
-// ScanLibrary scans the library from your Plex server via library key (or id)
-func (p *Plex) ScanLibrary(key string) error {
-	query := fmt.Sprintf("%s/library/sections/%s/refresh", p.URL, key)
+// SetRemoteAccess publishes the server to plex.tv for remote access, or stops publishing it, i.e. during maintenance
+func (p *Plex) SetRemoteAccess(enabled bool) error {
+	return p.SetSetting(remoteAccessSetting, strconv.FormatBool(enabled))
+}
+
+// GetButlerTasks returns the scheduled maintenance tasks of your Plex server
+func (p *Plex) GetButlerTasks() ([]ButlerTask, error) {
+	query := fmt.Sprintf("%s/butler", p.URL)
 
 	resp, err := p.get(query, p.Headers)
 
 	if err != nil {
-		return err
+		return []ButlerTask{}, err
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(resp.Status)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return []ButlerTask{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return []ButlerTask{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
-	return nil
+	var result butlerTasksResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return []ButlerTask{}, err
+	}
+
+	return result.ButlerTasks.ButlerTask, nil
 }
 
-// DeleteLibrary removes the library from your Plex server via library key (or id)
-func (p *Plex) DeleteLibrary(key string) error {
-	query := fmt.Sprintf("%s/library/sections/%s", p.URL, key)
+// butler settings of the scheduled window and the tasks, i.e. ButlerTaskBackupDatabase
+const (
+	butlerStartHourSetting  = "ButlerStartHour"
+	butlerEndHourSetting    = "ButlerEndHour"
+	butlerTaskSettingPrefix = "ButlerTask"
+)
 
-	resp, err := p.delete(query, p.Headers)
+// GetButlerSchedule returns the daily window of the butler tasks and which tasks are enabled
+func (p *Plex) GetButlerSchedule() (ButlerSchedule, error) {
+	settings, err := p.GetSettings()
 
 	if err != nil {
-		return err
+		return ButlerSchedule{}, err
 	}
 
-	defer resp.Body.Close()
+	schedule := ButlerSchedule{Tasks: map[string]bool{}}
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(resp.Status)
+	for _, setting := range settings {
+		switch {
+		case setting.ID == butlerStartHourSetting:
+			schedule.StartHour, err = strconv.Atoi(setting.Value)
+		case setting.ID == butlerEndHourSetting:
+			schedule.EndHour, err = strconv.Atoi(setting.Value)
+		case strings.HasPrefix(setting.ID, butlerTaskSettingPrefix) && setting.Type == "bool":
+			schedule.Tasks[strings.TrimPrefix(setting.ID, butlerTaskSettingPrefix)], err = strconv.ParseBool(setting.Value)
+		}
+
+		if err != nil {
+			return ButlerSchedule{}, fmt.Errorf("invalid value for setting %s: %s", setting.ID, setting.Value)
+		}
 	}
 
-	return nil
+	return schedule, nil
 }
 
-// GetLibraryLabels of your plex server
-func (p *Plex) GetLibraryLabels(sectionKey, sectionIndex string) (LibraryLabels, error) {
+// SetButlerSchedule changes the daily window of the butler tasks, from startHour to endHour (0 to 23)
+func (p *Plex) SetButlerSchedule(startHour, endHour int) error {
+	if startHour < 0 || startHour > 23 || endHour < 0 || endHour > 23 {
+		return fmt.Errorf("invalid butler schedule: %d to %d", startHour, endHour)
+	}
 
-	if sectionIndex == "" {
-		sectionIndex = "1"
+	if err := p.SetSetting(butlerStartHourSetting, strconv.Itoa(startHour)); err != nil {
+		return err
 	}
 
-	query := fmt.Sprintf("%s/library/sections/%s/labels?type=%s", p.URL, sectionKey, sectionIndex)
+	return p.SetSetting(butlerEndHourSetting, strconv.Itoa(endHour))
+}
 
-	resp, err := p.get(query, p.Headers)
+// RunButlerTask starts a butler task (i.e. BackupDatabase) now instead of waiting for its scheduled window.
+// Returns ErrUnknownButlerTask or ErrButlerTaskRunning when plex refuses to start the task
+func (p *Plex) RunButlerTask(taskName string) error {
+	if taskName == "" {
+		return errors.New("a task name is required")
+	}
+
+	query := fmt.Sprintf("%s/butler/%s", p.URL, url.PathEscape(taskName))
+
+	resp, err := p.post(query, nil, p.Headers)
 
 	if err != nil {
-		return LibraryLabels{}, err
+		return err
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return LibraryLabels{}, errors.New(resp.Status)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusAccepted:
+		return ErrButlerTaskRunning
+	case http.StatusNotFound:
+		return ErrUnknownButlerTask
+	case http.StatusUnauthorized:
+		return errors.New(ErrorNotAuthorized)
+	default:
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
+}
 
-	var result LibraryLabels
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		fmt.Println(err.Error())
+// GetActivities returns the activities the server is running. It is the polling counterpart of OnActivity
+func (p *Plex) GetActivities() ([]Activity, error) {
+	var result activitiesResponse
 
-		return LibraryLabels{}, err
+	if err := p.getJSON(fmt.Sprintf("%s/activities", p.URL), &result); err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	return result.MediaContainer.Activity, nil
 }
 
-// AddLabelToMedia restrict access to certain media. Requires a Plex Pass.
-// mediaType is the media type (1), id is the ratingKey or media id, label is your label, locked is unknown
-// 1. A reference to the plex media types: https://github.com/Arcanemagus/plex-api/wiki/MediaTypes
-// XXX: Currently plex is capitalizing the first letter
-func (p *Plex) AddLabelToMedia(mediaType, sectionID, id, label, locked string) (bool, error) {
+// CancelActivity cancels a running activity of the server, i.e. a library refresh. The uuid and whether
+// the activity is cancellable are sent in activity notifications. Returns ErrActivityNotFound when the
+// activity already finished.
+func (p *Plex) CancelActivity(uuid string) error {
+	if uuid == "" {
+		return fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
 
-	query := fmt.Sprintf("%s/library/sections/%s/all", p.URL, sectionID)
+	resp, err := p.delete(fmt.Sprintf("%s/activities/%s", p.URL, url.PathEscape(uuid)), p.Headers)
 
-	parsedQuery, parseErr := url.Parse(query)
+	if err != nil {
+		return err
+	}
 
-	if parseErr != nil {
-		return false, parseErr
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrActivityNotFound
+	case http.StatusUnauthorized:
+		return errors.New(ErrorNotAuthorized)
+	default:
+		return fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
+}
 
-	vals := parsedQuery.Query()
+// GetHistory returns the watch history of your Plex server, most recent first. All params are optional
+func (p *Plex) GetHistory(params HistoryParams) (History, error) {
+	query := fmt.Sprintf("%s/status/sessions/history/all?sort=viewedAt:desc", p.URL)
 
-	vals.Add("type", mediaType)
-	vals.Add("id", id)
-	vals.Add("label[0].tag.tag", label)
-	// vals.Add("label.locked", locked)
+	if params.AccountID != 0 {
+		query += fmt.Sprintf("&accountID=%d", params.AccountID)
+	}
 
-	parsedQuery.RawQuery = vals.Encode()
+	if params.SectionKey != "" {
+		query += "&librarySectionID=" + url.QueryEscape(params.SectionKey)
+	}
 
-	query = parsedQuery.String()
+	if !params.ViewedAfter.IsZero() {
+		query += fmt.Sprintf("&viewedAt>=%d", params.ViewedAfter.Unix())
+	}
 
-	resp, err := p.put(query, nil, p.Headers)
+	resp, err := p.get(query, p.Headers)
 
 	if err != nil {
-		return false, err
+		return History{}, err
 	}
 
 	defer resp.Body.Close()
 
-	return resp.StatusCode == http.StatusOK, nil
-}
-
-// RemoveLabelFromMedia to remove a label from a piece of media Requires a Plex Pass.
-func (p *Plex) RemoveLabelFromMedia(mediaType, sectionID, id, label, locked string) (bool, error) {
-
-	query := fmt.Sprintf("%s/library/sections/%s/all", p.URL, sectionID)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return History{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return History{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
 
-	parsedQuery, parseErr := url.Parse(query)
+	var result History
 
-	if parseErr != nil {
-		return false, parseErr
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return History{}, err
 	}
 
-	vals := parsedQuery.Query()
-
-	vals.Add("type", mediaType)
-	vals.Add("id", id)
-	vals.Add("label[].tag.tag-", label)
-	vals.Add("label.locked", locked)
+	return result, nil
+}
 
-	parsedQuery.RawQuery = vals.Encode()
+// GetStatistics returns samples of the server's bandwidth, cpu and memory usage aggregated
+// by timespan (i.e. StatisticsTimespanSeconds)
+func (p *Plex) GetStatistics(timespan int) (Statistics, error) {
+	var bandwidth statisticsBandwidthResponse
 
-	query = parsedQuery.String()
+	if err := p.getJSON(fmt.Sprintf("%s/statistics/bandwidth?timespan=%d", p.URL, timespan), &bandwidth); err != nil {
+		return Statistics{}, err
+	}
 
-	resp, err := p.put(query, nil, p.Headers)
+	var resources statisticsResourcesResponse
 
-	if err != nil {
-		return false, err
+	if err := p.getJSON(fmt.Sprintf("%s/statistics/resources?timespan=%d", p.URL, timespan), &resources); err != nil {
+		return Statistics{}, err
 	}
 
-	defer resp.Body.Close()
-
-	return resp.StatusCode == http.StatusOK, nil
+	return Statistics{
+		Bandwidth: bandwidth.MediaContainer.StatisticsBandwidth,
+		Resources: resources.MediaContainer.StatisticsResources,
+	}, nil
 }
 
-// GetSessions of devices currently consuming media
+// GetSessions returns the sessions of devices currently consuming media (/status/sessions). Every
+// session has the media being played along with its User, Player and Session. Transcoding sessions
+// also have a TranscodeSession, and the Decision of their parts is transcode.
 func (p *Plex) GetSessions() (CurrentSessions, error) {
 	newHeaders := p.Headers
 
@@ -1231,8 +3791,10 @@ func (p *Plex) GetSessions() (CurrentSessions, error) {
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return CurrentSessions{}, errors.New(resp.Status)
+	if resp.StatusCode == http.StatusUnauthorized {
+		return CurrentSessions{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return CurrentSessions{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
 	}
 
 	var result CurrentSessions
@@ -1244,6 +3806,108 @@ func (p *Plex) GetSessions() (CurrentSessions, error) {
 	return result, nil
 }
 
+// ActiveStreamCount returns the number of sessions on the server and how many of them are transcoding
+func (p *Plex) ActiveStreamCount() (total int, transcoding int, err error) {
+	sessions, err := p.GetSessions()
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, session := range sessions.MediaContainer.Metadata {
+		if session.IsTranscoding() {
+			transcoding++
+		}
+	}
+
+	return len(sessions.MediaContainer.Metadata), transcoding, nil
+}
+
+// defaultSessionPollInterval is how often WatchSessions polls when it is given no interval
+const defaultSessionPollInterval = 10 * time.Second
+
+// WatchSessions polls the sessions of the server every interval and sends the sessions that started,
+// stopped or changed state (i.e. paused) on the returned channel. Sessions playing when it is called are
+// sent as started. Polling errors are sent as SessionError events. The channel is closed once ctx is done.
+// An interval of 0 or less polls every 10 seconds.
+func (p *Plex) WatchSessions(ctx context.Context, interval time.Duration) <-chan SessionEvent {
+	if interval <= 0 {
+		interval = defaultSessionPollInterval
+	}
+
+	events := make(chan SessionEvent)
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		previous := map[string]MetadataV1{}
+
+		send := func(event SessionEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			sessions, err := p.GetSessions()
+
+			if err != nil {
+				if !send(SessionEvent{Type: SessionError, Err: err}) {
+					return
+				}
+			} else {
+				current := make(map[string]MetadataV1, len(sessions.MediaContainer.Metadata))
+
+				for _, session := range sessions.MediaContainer.Metadata {
+					current[session.SessionKey] = session
+
+					old, ok := previous[session.SessionKey]
+
+					var event SessionEvent
+
+					if !ok {
+						event = SessionEvent{Type: SessionStarted, Session: session}
+					} else if old.Player.State != session.Player.State {
+						event = SessionEvent{Type: SessionStateChanged, Session: session}
+					} else {
+						continue
+					}
+
+					if !send(event) {
+						return
+					}
+				}
+
+				for key, session := range previous {
+					if _, ok := current[key]; ok {
+						continue
+					}
+
+					if !send(SessionEvent{Type: SessionStopped, Session: session}) {
+						return
+					}
+				}
+
+				previous = current
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events
+}
+
 // TerminateSession will end a streaming session - plex pass feature
 func (p *Plex) TerminateSession(sessionID string, reason string) error {
 	if reason == "" {
@@ -1,14 +1,22 @@
 package plex
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 var (
@@ -44,7 +52,7 @@ func newTestServer(code int, body string) (*httptest.Server, *Plex) {
 	}
 
 	httpClient := http.Client{Transport: transport}
-	plex := &Plex{URL: server.URL, Token: "", HTTPClient: httpClient}
+	plex := &Plex{URL: server.URL, Token: "", HTTPClient: httpClient, PlexTVURL: server.URL}
 
 	return server, plex
 }
@@ -153,6 +161,11 @@ func TestCheckUsernameOrEmailResponse(t *testing.T) {
 
 	if err := xml.Unmarshal(testData, result); err != nil {
 		t.Error(err.Error())
+		return
+	}
+
+	if result.Status != "Valid user" {
+		t.Errorf("Expected: %s\n Got: %s", "Valid user", result.Status)
 	}
 }
 
@@ -185,9 +198,2121 @@ func TestInviteFriendResponse(t *testing.T) {
 		</MediaContainer>
 	`)
 
-	result := new(inviteFriendResponse)
+	result := new(SharedServer)
+
+	if err := xml.Unmarshal(testData, result); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetPlaylists(t *testing.T) {
+	testData := `{"MediaContainer":{"size":1,"Metadata":[{"ratingKey":"1234","key":"/playlists/1234/items","type":"playlist","title":"Nightly Mix","smart":false,"playlistType":"video","duration":7200000,"leafCount":3}]}}`
+
+	_, _plex := newTestServer(200, testData)
+
+	result, err := _plex.GetPlaylists()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(result.MediaContainer.Metadata) != 1 {
+		t.Errorf("Expected: 1 playlist\n Got: %d", len(result.MediaContainer.Metadata))
+		return
+	}
+
+	if result.MediaContainer.Metadata[0].RatingKey != "1234" {
+		t.Errorf("Expected: %s\n Got: %s", "1234", result.MediaContainer.Metadata[0].RatingKey)
+	}
+}
+
+func TestHomeUsersResponse(t *testing.T) {
+	testData := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+		<MediaContainer friendlyName="myPlex" identifier="com.plexapp.plugins.myplex" machineIdentifier="abc123" size="2">
+			<User id="1" uuid="abc" admin="1" guest="0" restricted="0" protected="1" title="jrudio" username="jrudio" email="jrudio@example.com" thumb=""/>
+			<User id="2" uuid="def" admin="0" guest="0" restricted="1" protected="0" title="kid" username="" email="" thumb=""/>
+		</MediaContainer>
+	`)
+
+	result := new(homeUsersResponse)
+
+	if err := xml.Unmarshal(testData, result); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(result.User) != 2 {
+		t.Errorf("Expected: 2 users\n Got: %d", len(result.User))
+		return
+	}
+
+	if !result.User[1].Restricted || result.User[1].Admin {
+		t.Error("expected the second user to be a restricted managed user")
+	}
+}
+
+func TestGetSettings(t *testing.T) {
+	testData := `{"MediaContainer":{"size":3,"Setting":[
+		{"id":"FriendlyName","label":"Friendly name","summary":"","type":"text","default":"","value":"media","hidden":false,"advanced":false,"group":"general"},
+		{"id":"ButlerStartHour","label":"Time at which tasks start to run","summary":"","type":"int","default":2,"value":3,"hidden":false,"advanced":false,"group":"butler","enumValues":"0:Midnight|1:1 am|2:2 am|3:3 am"},
+		{"id":"DlnaEnabled","label":"Enable the DLNA server","summary":"","type":"bool","default":true,"value":false,"hidden":false,"advanced":false,"group":"dlna"}
+	]}}`
+
+	_, _plex := newTestServer(200, testData)
+
+	settings, err := _plex.GetSettings()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(settings) != 3 {
+		t.Errorf("Expected: 3 settings\n Got: %d", len(settings))
+		return
+	}
+
+	if settings[1].Value != "3" || settings[2].Value != "false" {
+		t.Errorf("Expected: 3 and false\n Got: %s and %s", settings[1].Value, settings[2].Value)
+	}
+
+	options := settings[1].Options()
+
+	if len(options) != 4 || options[3].Label != "3 am" {
+		t.Errorf("unexpected options: %v", options)
+	}
+}
+
+func TestDownloadMediaRangeNotSupported(t *testing.T) {
+	_, _plex := newTestServer(200, "media")
+
+	var buf bytes.Buffer
+
+	if _, err := _plex.DownloadMedia(Part{Key: "/library/parts/1/file.mkv"}, &buf, 2, nil); !errors.Is(err, ErrRangeNotSupported) {
+		t.Errorf("Expected: %v\n Got: %v", ErrRangeNotSupported, err)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected: nothing written\n Got: %q", buf.String())
+	}
+}
+
+func TestDownloadMediaProgress(t *testing.T) {
+	_, _plex := newTestServer(200, "media")
+
+	var buf bytes.Buffer
+	var lastWritten, lastTotal int64
+
+	n, err := _plex.DownloadMedia(Part{Key: "/library/parts/1/file.mkv"}, &buf, 0, func(written, total int64) {
+		lastWritten, lastTotal = written, total
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	// the test server appends a newline to the body
+	if n != 6 || buf.String() != "media\n" {
+		t.Errorf("Expected: %d bytes\n Got: %d (%q)", 6, n, buf.String())
+	}
+
+	if lastWritten != 6 || lastTotal != 6 {
+		t.Errorf("Expected: progress 6/6\n Got: %d/%d", lastWritten, lastTotal)
+	}
+}
+
+func TestDownloadLogs(t *testing.T) {
+	_, _plex := newTestServer(200, "logs")
+
+	var buf bytes.Buffer
+
+	n, err := _plex.DownloadLogs(&buf)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if n != 5 || buf.String() != "logs\n" {
+		t.Errorf("Expected: %d bytes\n Got: %d (%q)", 5, n, buf.String())
+	}
+
+	_, _plex = newTestServer(http.StatusForbidden, "")
+
+	if _, err := _plex.DownloadLogs(&buf); err == nil || err.Error() != ErrorNotAuthorized {
+		t.Errorf("Expected: %s\n Got: %v", ErrorNotAuthorized, err)
+	}
+}
+
+func TestImageURL(t *testing.T) {
+	p := &Plex{URL: "http://192.168.1.2:32400", Token: "abc123"}
+
+	got := p.ImageURL("/library/metadata/1/thumb/1600000000", 300, 450)
+
+	expected := "http://192.168.1.2:32400/photo/:/transcode?X-Plex-Token=abc123&height=450&url=%2Flibrary%2Fmetadata%2F1%2Fthumb%2F1600000000&width=300"
+
+	if got != expected {
+		t.Errorf("Expected: %s\n Got: %s", expected, got)
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	transport := &http.Transport{}
+
+	p, err := New("http://192.168.1.2:32400", "abc123", WithTimeout(10*time.Second), WithTransport(transport))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if p.HTTPClient.Timeout != 10*time.Second {
+		t.Errorf("Expected: %s\n Got: %s", 10*time.Second, p.HTTPClient.Timeout)
+	}
+
+	if p.HTTPClient.Transport != transport || p.DownloadClient.Transport != transport {
+		t.Error("Expected: the transport to be used by both clients")
+	}
+
+	if p.DownloadClient.Timeout != 0 {
+		t.Errorf("Expected: downloads to not time out\n Got: %s", p.DownloadClient.Timeout)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var attempts int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+
+		if attempts < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		fmt.Fprintln(w, `{"MediaContainer":{"size":0}}`)
+	}))
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123", WithRetry(2, time.Millisecond, time.Millisecond))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if _, err := p.GetOnDeck(); err != nil {
+		t.Error(err.Error())
+	}
+
+	if attempts != 3 {
+		t.Errorf("Expected: %d attempts\n Got: %d", 3, attempts)
+	}
+
+	// requests other than GET are not retried
+	attempts = 0
+
+	if err := p.DeletePlaylist("1"); err == nil {
+		t.Error("Expected: an error\n Got: nil")
+	}
+
+	if attempts != 1 {
+		t.Errorf("Expected: %d attempt\n Got: %d", 1, attempts)
+	}
+}
+
+func TestRetryWithContext(t *testing.T) {
+	var attempts int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123", WithRetry(5, time.Hour, 0))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if _, err := p.WithContext(ctx).GetOnDeck(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected: %v\n Got: %v", context.DeadlineExceeded, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected: the retry to stop when the context is done\n Got: %s", elapsed)
+	}
+
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("Expected: %d attempt\n Got: %d", 1, n)
+	}
+}
+
+func TestGetServerCapabilities(t *testing.T) {
+	testData := `{"MediaContainer":{"friendlyName":"plex","machineIdentifier":"abc123","version":"1.32.5","platform":"Linux","hubSearch":true,"transcoderSubtitles":"1","transcoderVideo":"0","transcoderActiveVideoSessions":2}}`
+
+	_, _plex := newTestServer(200, testData)
+
+	result, err := _plex.GetServerCapabilities()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if result.MachineIdentifier != "abc123" || result.Version != "1.32.5" {
+		t.Errorf("Expected: abc123 1.32.5\n Got: %s %s", result.MachineIdentifier, result.Version)
+	}
+
+	if !result.HubSearch || !result.TranscoderSubtitles || result.TranscoderVideo {
+		t.Errorf("Expected: hub search and subtitle transcoding without video transcoding\n Got: %+v", result)
+	}
+
+	if result.TranscoderActiveVideoSessions != 2 {
+		t.Errorf("Expected: %d\n Got: %d", 2, result.TranscoderActiveVideoSessions)
+	}
+}
+
+func TestDevicesResponse(t *testing.T) {
+	testData := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+		<MediaContainer publicAddress="1.2.3.4">
+			<Device name="iPhone" publicAddress="1.2.3.4" product="Plex for iOS" productVersion="8.0" platform="iOS" platformVersion="17.0" device="iPhone" model="15" vendor="Apple" provides="client,player" clientIdentifier="abc" version="8.0" id="123" token="secret" createdAt="1600000000" lastSeenAt="1700000000" screenResolution="" screenDensity="">
+				<Connection uri="http://192.168.1.3:32500"/>
+			</Device>
+		</MediaContainer>
+	`)
+
+	result := new(resourcesResponse)
 
 	if err := xml.Unmarshal(testData, result); err != nil {
 		t.Error(err.Error())
+		return
+	}
+
+	if len(result.Device) != 1 {
+		t.Errorf("Expected: 1 device\n Got: %d", len(result.Device))
+		return
+	}
+
+	device := result.Device[0]
+
+	if device.ID != 123 || device.Token != "secret" {
+		t.Errorf("Expected: id 123 and token secret\n Got: %d %s", device.ID, device.Token)
+	}
+
+	if len(device.Connection) != 1 || device.Connection[0].URI != "http://192.168.1.3:32500" {
+		t.Errorf("Expected: 1 connection\n Got: %v", device.Connection)
+	}
+}
+
+func TestWithInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"MediaContainer":{"size":0}}`)
+	}))
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if _, err := p.GetOnDeck(); err == nil {
+		t.Error("Expected: a certificate error\n Got: nil")
+	}
+
+	p, err = New(server.URL, "abc123", WithInsecureSkipVerify())
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if _, err := p.GetOnDeck(); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetMetadataMulti(t *testing.T) {
+	var requests []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+
+		keys := strings.Split(strings.TrimPrefix(r.URL.Path, "/library/metadata/"), ",")
+
+		var metadata []string
+
+		for _, key := range keys {
+			metadata = append(metadata, fmt.Sprintf(`{"ratingKey":"%s"}`, key))
+		}
+
+		fmt.Fprintf(w, `{"MediaContainer":{"size":%d,"Metadata":[%s]}}`, len(keys), strings.Join(metadata, ","))
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	var keys []string
+
+	for ii := 0; ii < 1000; ii++ {
+		keys = append(keys, strconv.Itoa(100000+ii))
+	}
+
+	result, err := p.GetMetadataMulti(keys)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(requests) < 2 {
+		t.Errorf("Expected: the keys to be split in several requests\n Got: %d", len(requests))
+	}
+
+	if result.MediaContainer.Size != 1000 || result.MediaContainer.Metadata[999].RatingKey != "100999" {
+		t.Errorf("Expected: 1000 items in order\n Got: %d", result.MediaContainer.Size)
+	}
+}
+
+func TestGetPlayQueue(t *testing.T) {
+	testData := `{"MediaContainer":{"playQueueID":42,"playQueueSelectedItemID":101,"playQueueTotalCount":3,"size":3,"Metadata":[
+		{"ratingKey":"1","title":"Track 1","playQueueItemID":100},
+		{"ratingKey":"2","title":"Track 2","playQueueItemID":101},
+		{"ratingKey":"3","title":"Track 3","playQueueItemID":102}
+	]}}`
+
+	_, _plex := newTestServer(200, testData)
+
+	queue, err := _plex.GetPlayQueue(42)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if current, ok := queue.SelectedItem(); !ok || current.RatingKey != "2" {
+		t.Errorf("Expected: Track 2 to be selected\n Got: %+v", current)
+	}
+
+	if next, ok := queue.NextItem(); !ok || next.RatingKey != "3" {
+		t.Errorf("Expected: Track 3 to be next\n Got: %+v", next)
+	}
+}
+
+func TestGetClients(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/clients" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fmt.Fprint(w, `{"MediaContainer":{"size":2,"Server":[
+			{"name":"Living Room","machineIdentifier":"tv-id","product":"Plex for Android (TV)","protocolCapabilities":"timeline,playback,navigation,playqueues"},
+			{"name":"Plexamp","machineIdentifier":"amp-id","product":"Plexamp","protocolCapabilities":"timeline,playqueues"}
+		]}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	clients, err := p.GetClients()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(clients) != 1 || clients[0].MachineIdentifier != "tv-id" {
+		t.Errorf("Expected: only the client with the playback capability\n Got: %+v", clients)
+	}
+}
+
+func TestNavigate(t *testing.T) {
+	var request *http.Request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	if err := p.Navigate("client-id", NavigationSelect); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if request.URL.Path != "/player/navigation/select" || request.URL.Query().Get("commandID") == "" {
+		t.Errorf("Expected: a select command with a command id\n Got: %s", request.URL.RequestURI())
+	}
+
+	if got := request.Header.Get("X-Plex-Target-Client-Identifier"); got != "client-id" {
+		t.Errorf("Expected: client-id\n Got: %s", got)
+	}
+
+	if err := p.Navigate("client-id", "jump"); err == nil {
+		t.Error("Expected: an invalid command error\n Got: nil")
+	}
+}
+
+func TestSetStreams(t *testing.T) {
+	var request *http.Request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	if err := p.SetStreams("client-id", StreamTypeAudio, 42); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	query := request.URL.Query()
+
+	if request.URL.Path != "/player/playback/setStreams" || query.Get("audioStreamID") != "42" || query.Get("subtitleStreamID") != "" {
+		t.Errorf("Expected: a setStreams command with audio stream 42\n Got: %s", request.URL.RequestURI())
+	}
+
+	if got := request.Header.Get("X-Plex-Target-Client-Identifier"); got != "client-id" {
+		t.Errorf("Expected: client-id\n Got: %s", got)
+	}
+
+	if err := p.SetStreams("client-id", StreamTypeSubtitle, 0); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	query = request.URL.Query()
+
+	if query.Get("subtitleStreamID") != "0" || query.Get("audioStreamID") != "" {
+		t.Errorf("Expected: subtitles turned off\n Got: %s", request.URL.RequestURI())
+	}
+
+	if err := p.SetStreams("client-id", StreamTypeVideo, 1); err == nil {
+		t.Error("Expected: an invalid stream type error\n Got: nil")
+	}
+}
+
+func TestStopPlaybackNotRetried(t *testing.T) {
+	var requests int32
+	var request *http.Request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		request = r
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123", WithRetry(2, time.Millisecond, 0))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if err := p.StopPlayback("client-id"); err == nil {
+		t.Error("Expected: the error of the server\n Got: nil")
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected: the command to be sent once\n Got: %d requests", n)
+	}
+
+	if request.URL.Path != "/player/playback/stop" || request.URL.Query().Get("commandID") == "" {
+		t.Errorf("Expected: a stop command with a command id\n Got: %s", request.URL.RequestURI())
+	}
+}
+
+func TestGetStatistics(t *testing.T) {
+	var timespans []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timespans = append(timespans, r.URL.Query().Get("timespan"))
+
+		switch r.URL.Path {
+		case "/statistics/bandwidth":
+			fmt.Fprint(w, `{"MediaContainer":{"size":1,"StatisticsBandwidth":[{"accountID":1,"at":1700000000,"bytes":5242880,"deviceID":3,"lan":true,"timespan":6}]}}`)
+		case "/statistics/resources":
+			fmt.Fprint(w, `{"MediaContainer":{"size":1,"StatisticsResources":[{"at":1700000000,"hostCpuUtilization":12.5,"hostMemoryUtilization":40.1,"processCpuUtilization":3.2,"processMemoryUtilization":1.5,"timespan":6}]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	stats, err := p.GetStatistics(StatisticsTimespanSeconds)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(timespans) != 2 || timespans[0] != "6" || timespans[1] != "6" {
+		t.Errorf("Expected: both statistics requested with timespan 6\n Got: %v", timespans)
+	}
+
+	if len(stats.Bandwidth) != 1 || stats.Bandwidth[0].Bytes != 5242880 || !stats.Bandwidth[0].LAN || stats.Bandwidth[0].DeviceID != 3 {
+		t.Errorf("Expected: 5242880 bytes of lan bandwidth of device 3\n Got: %+v", stats.Bandwidth)
+	}
+
+	if len(stats.Resources) != 1 || stats.Resources[0].HostCPUUtilization != 12.5 || stats.Resources[0].ProcessMemoryUtilization != 1.5 {
+		t.Errorf("Expected: 12.5%% host cpu and 1.5%% process memory\n Got: %+v", stats.Resources)
+	}
+}
+
+func TestWatchSessions(t *testing.T) {
+	polls := []string{
+		`{"MediaContainer":{"size":1,"Metadata":[{"sessionKey":"1","title":"Alien","Player":{"state":"playing"}}]}}`,
+		`{"MediaContainer":{"size":2,"Metadata":[{"sessionKey":"1","title":"Alien","Player":{"state":"paused"}},{"sessionKey":"2","title":"Aliens","Player":{"state":"playing"}}]}}`,
+		`{"MediaContainer":{"size":1,"Metadata":[{"sessionKey":"2","title":"Aliens","Player":{"state":"playing"}}]}}`,
+	}
+
+	var poll int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if poll < len(polls) {
+			fmt.Fprintln(w, polls[poll])
+			poll++
+			return
+		}
+
+		fmt.Fprintln(w, polls[len(polls)-1])
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := p.WatchSessions(ctx, time.Millisecond)
+
+	expected := []struct{ eventType, sessionKey string }{
+		{SessionStarted, "1"},
+		{SessionStateChanged, "1"},
+		{SessionStarted, "2"},
+		{SessionStopped, "1"},
+	}
+
+	for _, e := range expected {
+		event := <-events
+
+		if event.Type != e.eventType || event.Session.SessionKey != e.sessionKey {
+			t.Errorf("Expected: %s %s\n Got: %s %s", e.eventType, e.sessionKey, event.Type, event.Session.SessionKey)
+		}
+	}
+
+	cancel()
+
+	for range events {
+	}
+}
+
+func TestWatchSessionsWithoutInterval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"MediaContainer":{"size":1,"Metadata":[{"sessionKey":"1","title":"Alien","Player":{"state":"playing"}}]}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a ticker with an interval of 0 would panic
+	events := p.WatchSessions(ctx, 0)
+
+	if event := <-events; event.Type != SessionStarted || event.Session.SessionKey != "1" {
+		t.Errorf("Expected: %s 1\n Got: %s %s", SessionStarted, event.Type, event.Session.SessionKey)
+	}
+
+	cancel()
+
+	for range events {
+	}
+}
+
+func TestGetLibraryContentPage(t *testing.T) {
+	var start, size string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start = r.Header.Get("X-Plex-Container-Start")
+		size = r.Header.Get("X-Plex-Container-Size")
+
+		fmt.Fprintln(w, `{"MediaContainer":{"offset":50,"size":1,"totalSize":51,"Metadata":[{"ratingKey":"51"}]}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	results, err := p.GetLibraryContentPage("1", "", 50, 50)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if start != "50" || size != "50" {
+		t.Errorf("Expected: container start 50 and size 50\n Got: %s %s", start, size)
+	}
+
+	if results.MediaContainer.Offset != 50 || results.MediaContainer.TotalSize != 51 {
+		t.Errorf("Expected: offset 50 of 51\n Got: %d of %d", results.MediaContainer.Offset, results.MediaContainer.TotalSize)
+	}
+}
+
+func TestGetSectionSize(t *testing.T) {
+	var size string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size = r.Header.Get("X-Plex-Container-Size")
+
+		fmt.Fprintln(w, `{"MediaContainer":{"offset":0,"size":0,"totalSize":1234}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	total, err := p.GetSectionSize("1")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if size != "0" || total != 1234 {
+		t.Errorf("Expected: 1234 items with a container size of 0\n Got: %d with %q", total, size)
+	}
+}
+
+func TestGetSectionByTitle(t *testing.T) {
+	testData := `{"MediaContainer":{"size":2,"Directory":[{"key":"1","title":"Movies","type":"movie"},{"key":"2","title":"TV Shows","type":"show"}]}}`
+
+	_, _plex := newTestServer(200, testData)
+
+	section, err := _plex.GetSectionByTitle("tv shows")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if section.Key != "2" {
+		t.Errorf("Expected: 2\n Got: %s", section.Key)
+	}
+
+	if _, err := _plex.GetSectionByTitle("Music"); err != ErrSectionNotFound {
+		t.Errorf("Expected: %v\n Got: %v", ErrSectionNotFound, err)
+	}
+}
+
+func TestUpdateLibrary(t *testing.T) {
+	var requests int
+	var query url.Values
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.Method != http.MethodPut || r.URL.Path != "/library/sections/1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		query = r.URL.Query()
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	if err := p.UpdateLibrary("1", CreateLibraryParams{Agent: "tv.plex.agents.movie", Scanner: "Plex Movie", LibraryType: "movie"}); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	expected := url.Values{"agent": {"tv.plex.agents.movie"}, "scanner": {"Plex Movie"}}
+
+	if query.Encode() != expected.Encode() {
+		t.Errorf("Expected: %s\n Got: %s", expected.Encode(), query.Encode())
+	}
+
+	if err := p.UpdateLibrary("1", CreateLibraryParams{LibraryType: "movie"}); err == nil {
+		t.Error("Expected: an error without any field to update\n Got: nil")
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected: %d request\n Got: %d", 1, requests)
+	}
+}
+
+func TestAddLibraryLocation(t *testing.T) {
+	locations := []string{"/media/movies"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			locations = nil
+
+			for _, location := range r.URL.Query()["location"] {
+				// the server can only see folders under /media
+				if strings.HasPrefix(location, "/media/") {
+					locations = append(locations, location)
+				}
+			}
+
+			return
+		}
+
+		var paths []string
+
+		for ii, location := range locations {
+			paths = append(paths, fmt.Sprintf(`{"id":%d,"path":"%s"}`, ii, location))
+		}
+
+		fmt.Fprintf(w, `{"MediaContainer":{"Directory":[{"key":"1","title":"Movies","agent":"tv.plex.agents.movie","Location":[%s]}]}}`, strings.Join(paths, ","))
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	if err := p.AddLibraryLocation("1", "/media/movies2"); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(locations) != 2 || locations[1] != "/media/movies2" {
+		t.Errorf("Expected: 2 locations\n Got: %v", locations)
+	}
+
+	if err := p.AddLibraryLocation("1", "/mnt/unreachable"); err == nil {
+		t.Error("Expected: an error for an inaccessible location\n Got: nil")
+	}
+
+	if err := p.RemoveLibraryLocation("1", "/media/movies"); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(locations) != 1 || locations[0] != "/media/movies2" {
+		t.Errorf("Expected: only /media/movies2\n Got: %v", locations)
+	}
+}
+
+func TestFixMatch(t *testing.T) {
+	var matched url.Values
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/library/metadata/42/matches":
+			fmt.Fprint(w, `{"MediaContainer":{"SearchResult":[{"guid":"plex://movie/1","name":"Heat","year":1995,"score":100},{"guid":"plex://movie/2","name":"Heat","year":1986,"score":90}]}}`)
+		case "/library/metadata/42/match":
+			if r.Method != http.MethodPut {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			matched = r.URL.Query()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	matches, err := p.GetMatches("42")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(matches) != 2 || matches[1].Year != 1986 {
+		t.Errorf("Expected: 2 matches\n Got: %+v", matches)
+		return
+	}
+
+	if err := p.FixMatch("42", matches[1].GUID, matches[1].Name); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if matched.Get("guid") != "plex://movie/2" || matched.Get("name") != "Heat" {
+		t.Errorf("Expected: guid plex://movie/2 and name Heat\n Got: %v", matched)
+	}
+}
+
+func TestWithClientInfo(t *testing.T) {
+	var received http.Header
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+
+		fmt.Fprint(w, `{"MediaContainer":{}}`)
+	}))
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123", WithClientInfo(ClientInfo{Product: "MyApp", Version: "1.2.3", ClientIdentifier: "my-app-id"}))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if _, err := p.GetLibraries(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	expected := map[string]string{
+		"X-Plex-Product":           "MyApp",
+		"X-Plex-Version":           "1.2.3",
+		"X-Plex-Client-Identifier": "my-app-id",
+		"X-Plex-Device":            defaultHeaders().Device,
+	}
+
+	for header, value := range expected {
+		if received.Get(header) != value {
+			t.Errorf("Expected: %s to be %s\n Got: %s", header, value, received.Get(header))
+		}
+	}
+}
+
+func TestGenerateClientIdentifier(t *testing.T) {
+	first, err := GenerateClientIdentifier()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	second, err := GenerateClientIdentifier()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if first == "" || first == second {
+		t.Errorf("Expected: two different identifiers\n Got: %s and %s", first, second)
+	}
+}
+
+func TestNewGeneratesClientIdentifier(t *testing.T) {
+	first, err := New("http://192.168.1.2:32400", "abc123")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	second, err := New("http://192.168.1.2:32400", "abc123")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if first.ClientIdentifier == "" || first.ClientIdentifier == second.ClientIdentifier || first.ClientIdentifier == defaultHeaders().ClientIdentifier {
+		t.Errorf("Expected: two generated identifiers\n Got: %s and %s", first.ClientIdentifier, second.ClientIdentifier)
+	}
+
+	if first.Headers.ClientIdentifier != first.ClientIdentifier {
+		t.Errorf("Expected: %s\n Got: %s", first.ClientIdentifier, first.Headers.ClientIdentifier)
+	}
+
+	p, err := New("http://192.168.1.2:32400", "abc123", WithClientInfo(ClientInfo{ClientIdentifier: "my-app-id"}))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if p.ClientIdentifier != "my-app-id" {
+		t.Errorf("Expected: %s\n Got: %s", "my-app-id", p.ClientIdentifier)
+	}
+}
+
+func TestPlexTVURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/users/signin":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":1,"username":"brw","authToken":"abc123"}`)
+		case "/api/servers":
+		case "/library/search", "/library/metadata/5d776b59ad5437001f79c6f8":
+			fmt.Fprint(w, `{"MediaContainer":{}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p, err := SignIn("brw", "hunter2", WithPlexTVURL(server.URL))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if p.Token != "abc123" {
+		t.Errorf("Expected: %s\n Got: %s", "abc123", p.Token)
+	}
+
+	if p.PlexTVURL != server.URL {
+		t.Errorf("Expected: %s\n Got: %s", server.URL, p.PlexTVURL)
+	}
+
+	p, err = New("", "abc123", WithPlexTVURL(server.URL+"/"))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if _, err := p.Test(); err != nil {
+		t.Error(err.Error())
+	}
+
+	if _, err := p.DiscoverSearch("alien"); err != nil {
+		t.Error(err.Error())
+	}
+
+	if _, err := p.GetDiscoverMetadata("5d776b59ad5437001f79c6f8"); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetSessionsTranscode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"MediaContainer":{"size":2,"Metadata":[
+			{"sessionKey":"1","title":"Direct","Media":[{"id":2135,"aspectRatio":1.78,"Part":[{"id":2140,"decision":"directplay","Stream":[{"id":4397,"streamType":1}]}]}],
+				"User":{"id":"1","title":"brw"},"Player":{"title":"Chrome","state":"playing"},"Session":{"id":"abc","bandwidth":3000,"location":"lan"}},
+			{"sessionKey":"2","title":"Transcode","Media":[{"id":"2816","aspectRatio":"1.78","selected":true,"Part":[{"id":"2821","decision":"transcode","Stream":[{"id":"5760","streamType":1,"decision":"transcode"}]}]}],
+				"User":{"id":"2","title":"guest"},"Player":{"title":"Roku","state":"paused"},"Session":{"id":"def","bandwidth":8000,"location":"wan"},
+				"TranscodeSession":{"key":"/transcode/sessions/xyz","videoDecision":"transcode","audioDecision":"copy","progress":12.5,"speed":1.5,"throttled":false}}]}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	sessions, err := p.GetSessions()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(sessions.MediaContainer.Metadata) != 2 {
+		t.Errorf("Expected: 2 sessions\n Got: %d", len(sessions.MediaContainer.Metadata))
+		return
+	}
+
+	direct, transcode := sessions.MediaContainer.Metadata[0], sessions.MediaContainer.Metadata[1]
+
+	if direct.TranscodeSession != nil || direct.User.Title != "brw" || direct.Session.Location != "lan" {
+		t.Errorf("Expected: a direct play session of brw on the lan\n Got: %+v", direct)
+	}
+
+	if transcode.TranscodeSession == nil || transcode.TranscodeSession.VideoDecision != "transcode" {
+		t.Errorf("Expected: a transcode session\n Got: %+v", transcode.TranscodeSession)
+	}
+
+	if transcode.Media[0].ID != 2816 || transcode.Media[0].Part[0].ID != 2821 || transcode.Media[0].Part[0].Decision != "transcode" {
+		t.Errorf("Expected: media 2816 with transcoded part 2821\n Got: %+v", transcode.Media[0])
+	}
+}
+
+func TestActiveStreamCount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"MediaContainer":{"size":3,"Metadata":[
+			{"sessionKey":"1","Media":[{"Part":[{"decision":"directplay"}]}]},
+			{"sessionKey":"2","Media":[{"Part":[{"decision":"transcode"}]}],"TranscodeSession":{"videoDecision":"copy","audioDecision":"copy"}},
+			{"sessionKey":"3","Media":[{"Part":[{"decision":"transcode"}]}],"TranscodeSession":{"videoDecision":"transcode","audioDecision":"copy"}}]}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	total, transcoding, err := p.ActiveStreamCount()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if total != 3 || transcoding != 1 {
+		t.Errorf("Expected: 3 streams with 1 transcoding\n Got: %d streams with %d transcoding", total, transcoding)
+	}
+}
+
+func TestOptimizeMedia(t *testing.T) {
+	var optimized url.Values
+	var deleted bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/":
+			fmt.Fprint(w, `{"MediaContainer":{"machineIdentifier":"abc123"}}`)
+		case r.URL.Path == "/library/metadata/100":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"100","title":"Big Buck Bunny"}]}}`)
+		case r.URL.Path == "/playlists" && r.URL.Query().Get("type") == "42":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"2222","title":"Optimized Versions"}]}}`)
+		case r.URL.Path == "/playlists/2222/items" && r.Method == http.MethodPut:
+			optimized = r.URL.Query()
+			// a job for another item created concurrently is returned as well
+			fmt.Fprint(w, `{"MediaContainer":{"Item":[
+				{"id":7,"title":"Big Buck Bunny","targetTagID":1,"type":42,"Location":[{"uri":"server://abc123/com.plexapp.plugins.library/library/metadata/100"}]},
+				{"id":8,"title":"Sintel","targetTagID":1,"type":42,"Location":[{"uri":"server://abc123/com.plexapp.plugins.library/library/metadata/101"}]}
+			]}}`)
+		case r.URL.Path == "/playlists/2222/items/7" && r.Method == http.MethodDelete:
+			deleted = true
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	id, err := p.OptimizeMedia("100", OptimizeTargetMobile)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if id != 7 {
+		t.Errorf("Expected: job %d\n Got: %d", 7, id)
+	}
+
+	if optimized.Get("Item[targetTagID]") != "1" || optimized.Get("Item[title]") != "Big Buck Bunny" {
+		t.Errorf("Expected: a mobile version of Big Buck Bunny\n Got: %v", optimized)
+	}
+
+	if uri := optimized.Get("Item[Location][uri]"); uri != "server://abc123/com.plexapp.plugins.library/library/metadata/100" {
+		t.Errorf("Expected: the uri of the media\n Got: %s", uri)
+	}
+
+	if err := p.DeleteOptimizedVersion(id); err != nil {
+		t.Error(err.Error())
+	}
+
+	if !deleted {
+		t.Error("Expected: the job to be deleted from the background processing playlist\n Got: not deleted")
+	}
+}
+
+func TestCreateSyncItem(t *testing.T) {
+	var synced url.Values
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `{"MediaContainer":{"machineIdentifier":"abc123"}}`)
+		case "/library/metadata/200":
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"200","type":"episode","title":"Pilot","grandparentTitle":"Some Show"}]}}`)
+		case "/devices/tablet-id/sync_items":
+			synced = r.URL.Query()
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `<SyncItem id="42" version="1" rootTitle="Some Show" title="Pilot" metadataType="4" contentType="video" machineIdentifier="abc123"><Status state="pending" itemsCount="1"/></SyncItem>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL, PlexTVURL: server.URL}
+
+	item, err := p.CreateSyncItem("200", "tablet-id", SyncQuality720p)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if item.ID != 42 || item.Status.State != "pending" {
+		t.Errorf("Expected: pending sync item 42\n Got: %+v", item)
+	}
+
+	if synced.Get("SyncItem[rootTitle]") != "Some Show" || synced.Get("SyncItem[MediaSettings][maxVideoBitrate]") != "4000" {
+		t.Errorf("Expected: Some Show synced at 4000 kbps\n Got: %v", synced)
+	}
+}
+
+func TestGetRelated(t *testing.T) {
+	var path string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+
+		fmt.Fprint(w, `{"MediaContainer":{"size":2,"Hub":[
+			{"title":"More Like This","hubIdentifier":"movie.similar","type":"movie","size":2,"Metadata":[{"ratingKey":"11","title":"Aliens"},{"ratingKey":"12","title":"Predator"}]},
+			{"title":"More Directed by Ridley Scott","hubIdentifier":"movie.director","type":"movie","size":1,"Metadata":[{"ratingKey":"13","title":"Blade Runner"}]}
+		]}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	hubs, err := p.GetRelated("10")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if path != "/library/metadata/10/related" {
+		t.Errorf("Expected: /library/metadata/10/related\n Got: %s", path)
+	}
+
+	related := hubs.MediaContainer.Hub
+
+	if len(related) != 2 || related[0].Title != "More Like This" || len(related[0].Metadata) != 2 || related[1].Metadata[0].Title != "Blade Runner" {
+		t.Errorf("Expected: the similar and director hubs\n Got: %+v", related)
+	}
+
+	if _, err := p.GetRelated(""); err == nil {
+		t.Error("Expected: an error without a rating key\n Got: nil")
+	}
+}
+
+func TestGetHubs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hubs":
+			fmt.Fprint(w, `{"MediaContainer":{"size":1,"Hub":[{"title":"Continue Watching","more":false}]}}`)
+		case "/hubs/sections/1":
+			fmt.Fprint(w, `{"MediaContainer":{"size":2,"Hub":[{"title":"Recently Added Movies","more":true,"Metadata":[{"title":"Heat"}]},{"title":"Top Rated","more":false}]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	hubs, err := p.GetHubs("1")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(hubs.MediaContainer.Hub) != 2 || !hubs.MediaContainer.Hub[0].More || hubs.MediaContainer.Hub[0].Metadata[0].Title != "Heat" {
+		t.Errorf("Expected: the hubs of section 1\n Got: %+v", hubs.MediaContainer.Hub)
+	}
+
+	hubs, err = p.GetHubs("")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(hubs.MediaContainer.Hub) != 1 {
+		t.Errorf("Expected: the global hubs\n Got: %+v", hubs.MediaContainer.Hub)
+	}
+}
+
+func TestPing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/identity" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		fmt.Fprint(w, `{"MediaContainer":{"machineIdentifier":"abc123"}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	latency, err := p.Ping()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if latency < 10*time.Millisecond {
+		t.Errorf("Expected: a latency of at least 10ms\n Got: %s", latency)
+	}
+}
+
+func TestPingSkipsLimiterAndRetries(t *testing.T) {
+	var attempts int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	// going through the limiter, the second ping would wait an hour, and retrying would take hours
+	p, err := New(server.URL, "abc123", WithRetry(3, time.Hour, 0), WithRateLimit(1.0/3600, 1))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	for ii := 0; ii < 2; ii++ {
+		if _, err := p.Ping(); err == nil {
+			t.Error("Expected: an error\n Got: nil")
+		}
+	}
+
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Errorf("Expected: %d attempts\n Got: %d", 2, n)
+	}
+}
+
+func TestResolveServerName(t *testing.T) {
+	var requests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		fmt.Fprint(w, `<MediaContainer size="2">
+			<Device name="Home Server" provides="server" clientIdentifier="abc123"/>
+			<Device name="iPhone" provides="client,player" clientIdentifier="def456"/>
+		</MediaContainer>`)
+	}))
+	defer server.Close()
+
+	p, err := New("", "abc123", WithPlexTVURL(server.URL))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	for ii := 0; ii < 2; ii++ {
+		name, err := p.ResolveServerName("abc123")
+
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if name != "Home Server" {
+			t.Errorf("Expected: %s\n Got: %s", "Home Server", name)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected: the servers to be fetched once\n Got: %d", requests)
+	}
+
+	for ii := 0; ii < 2; ii++ {
+		if _, err := p.ResolveServerName("def456"); err != ErrServerNotFound {
+			t.Errorf("Expected: %v\n Got: %v", ErrServerNotFound, err)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected: misses to be cached\n Got: %d requests", requests)
+	}
+}
+
+func TestResolveServerNameExpires(t *testing.T) {
+	defer func(ttl time.Duration) { serverNameTTL = ttl }(serverNameTTL)
+	serverNameTTL = 200 * time.Millisecond
+
+	var requests int32
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		<-release
+
+		fmt.Fprint(w, `<MediaContainer size="1">
+			<Device name="Home Server" provides="server" clientIdentifier="abc123"/>
+		</MediaContainer>`)
+	}))
+	defer server.Close()
+
+	p, err := New("", "abc123", WithPlexTVURL(server.URL))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	for ii := 0; ii < 5; ii++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := p.ResolveServerName("abc123"); err != nil {
+				t.Error(err.Error())
+			}
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected: concurrent lookups to share a fetch\n Got: %d requests", n)
+	}
+
+	time.Sleep(serverNameTTL)
+
+	if _, err := p.ResolveServerName("abc123"); err != nil {
+		t.Error(err.Error())
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Expected: the names to be fetched again once expired\n Got: %d requests", n)
+	}
+}
+
+func TestWithLanguage(t *testing.T) {
+	var language string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		language = r.Header.Get("X-Plex-Language")
+
+		fmt.Fprint(w, `{"MediaContainer":{}}`)
+	}))
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123", WithLanguage("de"))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if _, err := p.GetHubs(""); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if language != "de" {
+		t.Errorf("Expected: %s\n Got: %s", "de", language)
+	}
+}
+
+func TestWithResponseHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"MediaContainer":{"size":1,"Hub":[{"title":"Recently Added"}]}}`)
+	}))
+	defer server.Close()
+
+	var logged string
+
+	p, err := New(server.URL, "abc123", WithResponseHook(func(resp *http.Response) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		logged = string(body)
+	}))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	hubs, err := p.GetHubs("")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if !strings.Contains(logged, "Recently Added") {
+		t.Errorf("Expected: the hook to receive the body\n Got: %s", logged)
+	}
+
+	if len(hubs.MediaContainer.Hub) != 1 {
+		t.Errorf("Expected: the body to still be decoded\n Got: %+v", hubs)
+	}
+}
+
+func TestWaitForScan(t *testing.T) {
+	var polls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+
+		fmt.Fprintf(w, `{"MediaContainer":{"Directory":[{"key":"1","title":"Movies","refreshing":%t}]}}`, polls < 3)
+	}))
+	defer server.Close()
+
+	defaultInterval := scanPollInterval
+	scanPollInterval = time.Millisecond
+	defer func() { scanPollInterval = defaultInterval }()
+
+	p := &Plex{URL: server.URL}
+
+	if err := p.WaitForScan(context.Background(), "1"); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if polls != 3 {
+		t.Errorf("Expected: 3 polls\n Got: %d", polls)
+	}
+
+	polls = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := p.WaitForScan(ctx, "1"); err != context.Canceled {
+		t.Errorf("Expected: %v\n Got: %v", context.Canceled, err)
+	}
+}
+
+func TestWaitForScanNotStarted(t *testing.T) {
+	var polls int
+
+	// the scan starts on the third poll and finishes on the fifth
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+
+		fmt.Fprintf(w, `{"MediaContainer":{"Directory":[{"key":"1","title":"Movies","refreshing":%t}]}}`, polls >= 3 && polls < 5)
+	}))
+	defer server.Close()
+
+	defaultInterval, defaultTimeout := scanPollInterval, scanStartTimeout
+	scanPollInterval = time.Millisecond
+	scanStartTimeout = time.Minute
+	defer func() { scanPollInterval, scanStartTimeout = defaultInterval, defaultTimeout }()
+
+	p := &Plex{URL: server.URL}
+
+	if err := p.WaitForScan(context.Background(), "1"); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if polls != 5 {
+		t.Errorf("Expected: 5 polls\n Got: %d", polls)
+	}
+
+	// a scan that never starts returns after scanStartTimeout
+	polls = -100
+	scanStartTimeout = 10 * time.Millisecond
+
+	if err := p.WaitForScan(context.Background(), "1"); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetAgentsAndScanners(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/system/agents" && r.URL.Query().Get("mediaType") == "1":
+			fmt.Fprint(w, `{"MediaContainer":{"Agent":[{"identifier":"tv.plex.agents.movie","name":"Plex Movie","primary":true,"MediaType":[{"mediaType":1,"name":"movie"}]}]}}`)
+		case r.URL.Path == "/system/scanners/1":
+			fmt.Fprint(w, `{"MediaContainer":{"Scanner":[{"name":"Plex Movie"},{"name":"Plex Video Files Scanner"}]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	agents, err := p.GetAgents("movie")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(agents) != 1 || agents[0].Identifier != "tv.plex.agents.movie" {
+		t.Errorf("Expected: the tv.plex.agents.movie agent\n Got: %+v", agents)
+	}
+
+	scanners, err := p.GetScanners("movie")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(scanners) != 2 || scanners[0].Name != "Plex Movie" {
+		t.Errorf("Expected: 2 scanners\n Got: %+v", scanners)
+	}
+}
+
+func TestActivities(t *testing.T) {
+	activities := map[string]bool{"abc-123": true}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uuid := strings.TrimPrefix(r.URL.Path, "/activities/")
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/activities":
+			fmt.Fprint(w, `{"MediaContainer":{"size":1,"Activity":[{"uuid":"abc-123","type":"library.update.section","cancellable":true,"title":"Scanning Movies","subtitle":"Heat","progress":42}]}}`)
+		case r.Method == http.MethodDelete && activities[uuid]:
+			delete(activities, uuid)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	result, err := p.GetActivities()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(result) != 1 || !result[0].Cancellable || result[0].Progress != 42 {
+		t.Errorf("Expected: a cancellable activity at 42%%\n Got: %+v", result)
+		return
+	}
+
+	if err := p.CancelActivity(result[0].UUID); err != nil {
+		t.Error(err.Error())
+	}
+
+	if err := p.CancelActivity(result[0].UUID); !errors.Is(err, ErrActivityNotFound) {
+		t.Errorf("Expected: %v\n Got: %v", ErrActivityNotFound, err)
+	}
+}
+
+func TestArtURLs(t *testing.T) {
+	p := &Plex{URL: "http://192.168.1.2:32400", Token: "abc123"}
+
+	episode := Metadata{
+		Thumb:            "/library/metadata/10/thumb/1",
+		GrandparentArt:   "/library/metadata/1/art/2",
+		GrandparentTheme: "/library/metadata/1/theme/3",
+		Image:            []DiscoverImage{{Type: "clearLogo", URL: "https://metadata-static.plex.tv/logo.png"}},
+	}
+
+	expected := map[string]string{
+		"poster": "http://192.168.1.2:32400/library/metadata/10/thumb/1?X-Plex-Token=abc123",
+		"art":    "http://192.168.1.2:32400/library/metadata/1/art/2?X-Plex-Token=abc123",
+		"theme":  "http://192.168.1.2:32400/library/metadata/1/theme/3?X-Plex-Token=abc123",
+		"logo":   "https://metadata-static.plex.tv/logo.png",
+		"banner": "",
+	}
+
+	got := map[string]string{
+		"poster": p.PosterURL(episode),
+		"art":    p.ArtURL(episode),
+		"theme":  p.ThemeURL(episode),
+		"logo":   p.LogoURL(episode),
+		"banner": p.BannerURL(episode),
+	}
+
+	for art, url := range expected {
+		if got[art] != url {
+			t.Errorf("%s: Expected: %s\n Got: %s", art, url, got[art])
+		}
+	}
+}
+
+func TestGetMetadataMarkers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("includeMarkers") != "1" || r.URL.Query().Get("includeChapters") != "1" {
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"10"}]}}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"10",
+			"Marker":[{"id":1,"type":"intro","startTimeOffset":30000,"endTimeOffset":90000},{"id":2,"type":"credits","startTimeOffset":1200000,"endTimeOffset":1260000,"final":true}],
+			"Chapter":[{"id":3,"index":1,"tag":"Opening","startTimeOffset":0,"endTimeOffset":90000}]}]}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	result, err := p.GetMetadata("10", IncludeMarkers, IncludeChapters)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(result.MediaContainer.Metadata) != 1 {
+		t.Errorf("Expected: 1 result\n Got: %d", len(result.MediaContainer.Metadata))
+		return
+	}
+
+	metadata := result.MediaContainer.Metadata[0]
+
+	if len(metadata.Marker) != 2 || len(metadata.Chapter) != 1 {
+		t.Errorf("Expected: 2 markers and 1 chapter\n Got: %+v and %+v", metadata.Marker, metadata.Chapter)
+		return
+	}
+
+	intro := metadata.Marker[0]
+
+	if intro.Type != MarkerTypeIntro || intro.Start() != 30*time.Second || intro.End() != 90*time.Second {
+		t.Errorf("Expected: an intro from 30s to 1m30s\n Got: %s from %s to %s", intro.Type, intro.Start(), intro.End())
+	}
+
+	if !metadata.Marker[1].Final || metadata.Chapter[0].Tag != "Opening" {
+		t.Errorf("Expected: final credits and an Opening chapter\n Got: %+v and %+v", metadata.Marker[1], metadata.Chapter[0])
+	}
+}
+
+func TestGetExtras(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/library/metadata/10/extras" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fmt.Fprint(w, `{"MediaContainer":{"size":2,"Metadata":[
+			{"ratingKey":"11","type":"clip","title":"Trailer","extraType":1,"subtype":"trailer"},
+			{"ratingKey":"12","type":"clip","title":"Alternate Ending","extraType":2,"subtype":"deletedScene"}]}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	result, err := p.GetExtras("10")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	extras := result.MediaContainer.Metadata
+
+	if len(extras) != 2 {
+		t.Errorf("Expected: 2 extras\n Got: %d", len(extras))
+		return
+	}
+
+	if extras[0].ExtraType != ExtraTypeTrailer || extras[0].Subtype != "trailer" {
+		t.Errorf("Expected: a trailer\n Got: %d %s", extras[0].ExtraType, extras[0].Subtype)
+	}
+
+	if extras[1].ExtraType != ExtraTypeDeletedScene || extras[1].Subtype != "deletedScene" {
+		t.Errorf("Expected: a deleted scene\n Got: %d %s", extras[1].ExtraType, extras[1].Subtype)
+	}
+
+	if _, err := p.GetExtras(""); err == nil {
+		t.Error("Expected: an error without a rating key")
+	}
+}
+
+func TestMergeItems(t *testing.T) {
+	var requests []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		requests = append(requests, r.URL.RequestURI())
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	if err := p.MergeItems("10", nil); err == nil {
+		t.Error("Expected: an error without rating keys to merge")
+	}
+
+	if err := p.MergeItems("10", []string{"10"}); err == nil {
+		t.Error("Expected: an error when merging an item into itself")
+	}
+
+	if err := p.MergeItems("10", []string{"11", "12"}); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if err := p.SplitItem("10"); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	expected := []string{"/library/metadata/10/merge?ids=11%2C12", "/library/metadata/10/split"}
+
+	if strings.Join(requests, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected: %v\n Got: %v", expected, requests)
+	}
+}
+
+func TestSetPoster(t *testing.T) {
+	var selected string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/library/metadata/10/posters":
+			fmt.Fprint(w, `{"MediaContainer":{"size":2,"Metadata":[
+				{"key":"/library/metadata/10/file?url=upload","ratingKey":"upload://posters/1","selected":true,"thumb":"/library/metadata/10/thumb"},
+				{"key":"https://image.tmdb.org/t/p/original/2.jpg","ratingKey":"metadata://posters/tmdb_2","selected":false,"provider":"com.plexapp.agents.themoviedb"}]}}`)
+		case r.Method == http.MethodPut && r.URL.Path == "/library/metadata/10/poster":
+			selected = r.URL.Query().Get("url")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	posters, err := p.GetPosters("10")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(posters) != 2 || !posters[0].Selected || posters[1].Provider != "com.plexapp.agents.themoviedb" {
+		t.Errorf("Expected: 2 posters, the first one selected\n Got: %+v", posters)
+		return
+	}
+
+	if err := p.SetPoster("10", posters[1].RatingKey); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if selected != "metadata://posters/tmdb_2" {
+		t.Errorf("Expected: metadata://posters/tmdb_2\n Got: %s", selected)
+	}
+
+	if _, err := p.GetArts("10"); err == nil {
+		t.Error("Expected: an error for the arts, which the server does not have")
+	}
+}
+
+func TestUploadPoster(t *testing.T) {
+	var contentType, uploaded string
+	var uploads int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			uploads++
+			contentType = r.Header.Get("Content-Type")
+			uploaded = r.URL.Query().Get("url")
+		case http.MethodGet:
+			// the server keeps the previous poster selected
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"metadata://posters/tmdb_2","selected":true}`)
+
+			for i := 1; i <= uploads; i++ {
+				fmt.Fprintf(w, `,{"ratingKey":"upload://posters/%d","selected":false}`, i)
+			}
+
+			fmt.Fprint(w, `]}}`)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL, Headers: headers{ContentType: "application/json"}}
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	key, err := p.UploadPoster("10", bytes.NewReader(png))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if key != "upload://posters/1" || contentType != "image/png" {
+		t.Errorf("Expected: upload://posters/1 uploaded as image/png\n Got: %s uploaded as %s", key, contentType)
+	}
+
+	if _, err := p.UploadPoster("10", strings.NewReader("not an image")); err == nil {
+		t.Error("Expected: an error for a poster that is not an image")
+	}
+
+	key, err = p.UploadPosterFromURL("10", "https://example.com/poster.jpg")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if key != "upload://posters/2" || uploaded != "https://example.com/poster.jpg" {
+		t.Errorf("Expected: upload://posters/2 downloaded from https://example.com/poster.jpg\n Got: %s downloaded from %s", key, uploaded)
+	}
+
+	if contentType != "" {
+		t.Errorf("Expected: no content type without a body\n Got: %s", contentType)
+	}
+}
+
+func TestUploadPosterNotAdded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"MediaContainer":{"Metadata":[{"ratingKey":"upload://posters/1","selected":true}]}}`)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	if key, err := p.UploadPosterFromURL("10", "https://example.com/poster.jpg"); err == nil {
+		t.Errorf("Expected: an error when no poster was added\n Got: %s", key)
+	}
+}
+
+func TestRemoteAccess(t *testing.T) {
+	published := "true"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/myplex/account":
+			fmt.Fprint(w, `{"MyPlex":{"mappingState":"mapped","mappingError":"","signInState":"ok","publicAddress":"203.0.113.4","publicPort":"32400","privateAddress":"192.168.1.2","privatePort":"32400"}}`)
+		case r.URL.Path == "/:/prefs" && r.Method == http.MethodGet:
+			fmt.Fprintf(w, `{"MediaContainer":{"size":1,"Setting":[{"id":"PublishServerOnPlexOnlineKey","type":"bool","default":false,"value":%s}]}}`, published)
+		case r.URL.Path == "/:/prefs" && r.Method == http.MethodPut:
+			published = r.URL.Query().Get("PublishServerOnPlexOnlineKey")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	state, err := p.GetRemoteAccessState()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if !state.Published || state.MappingState != MappingStateMapped || state.PublicAddress != "203.0.113.4" || state.PublicPort != 32400 {
+		t.Errorf("Expected: a published and mapped server\n Got: %+v", state)
+	}
+
+	if err := p.SetRemoteAccess(false); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if state, err = p.GetRemoteAccessState(); err != nil || state.Published {
+		t.Errorf("Expected: an unpublished server\n Got: %+v (%v)", state, err)
+	}
+}
+
+func TestTranscodeSessions(t *testing.T) {
+	tests := []string{
+		`{"_elementType":"MediaContainer","_children":[{"_elementType":"TranscodeSession","key":"abc","progress":42.5,"speed":0.4,"remaining":120,"audioChannels":2,"videoDecision":"transcode"}]}`,
+		`{"MediaContainer":{"size":1,"TranscodeSession":[{"key":"abc","progress":42.5,"speed":0.4,"remaining":120,"audioChannels":2,"videoDecision":"transcode"}]}}`,
+	}
+
+	for _, testData := range tests {
+		_, _plex := newTestServer(200, testData)
+
+		result, err := _plex.GetTranscodeSessions()
+
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		sessions := result.Sessions()
+
+		if len(sessions) != 1 {
+			t.Errorf("Expected: 1 session\n Got: %d", len(sessions))
+			continue
+		}
+
+		if sessions[0].Key != "abc" || sessions[0].Speed != 0.4 || sessions[0].Remaining != 120 || sessions[0].VideoDecision != "transcode" {
+			t.Errorf("Expected: session abc at 0.4x\n Got: %+v", sessions[0])
+		}
+	}
+}
+
+func TestRunButlerTask(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/butler/BackupDatabase":
+			w.WriteHeader(http.StatusOK)
+		case "/butler/CleanOldBundles":
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	if err := p.RunButlerTask("BackupDatabase"); err != nil {
+		t.Error(err.Error())
+	}
+
+	if err := p.RunButlerTask("CleanOldBundles"); !errors.Is(err, ErrButlerTaskRunning) {
+		t.Errorf("Expected: %v\n Got: %v", ErrButlerTaskRunning, err)
+	}
+
+	if err := p.RunButlerTask("NotATask"); !errors.Is(err, ErrUnknownButlerTask) {
+		t.Errorf("Expected: %v\n Got: %v", ErrUnknownButlerTask, err)
+	}
+}
+
+func TestGetButlerSchedule(t *testing.T) {
+	testData := `{"MediaContainer":{"size":4,"Setting":[
+		{"id":"ButlerStartHour","type":"int","default":2,"value":23},
+		{"id":"ButlerEndHour","type":"int","default":5,"value":4},
+		{"id":"ButlerTaskBackupDatabase","type":"bool","default":true,"value":true},
+		{"id":"ButlerTaskDeepMediaAnalysis","type":"bool","default":true,"value":false},
+		{"id":"ButlerDatabaseBackupPath","type":"text","default":"","value":"/backups"}
+	]}}`
+
+	_, _plex := newTestServer(200, testData)
+
+	schedule, err := _plex.GetButlerSchedule()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if schedule.StartHour != 23 || schedule.EndHour != 4 {
+		t.Errorf("Expected: 23 to 4\n Got: %d to %d", schedule.StartHour, schedule.EndHour)
+	}
+
+	if len(schedule.Tasks) != 2 || !schedule.Tasks["BackupDatabase"] || schedule.Tasks["DeepMediaAnalysis"] {
+		t.Errorf("Expected: BackupDatabase enabled and DeepMediaAnalysis disabled\n Got: %v", schedule.Tasks)
+	}
+
+	if err := _plex.SetButlerSchedule(22, 24); err == nil {
+		t.Error("Expected: an error for an invalid hour")
+	}
+}
+
+func TestGetUserRestrictions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/shared_servers/owned/accepted" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fmt.Fprint(w, `[
+			{"id":1,"machineIdentifier":"abc","invitedId":42,"invited":{"id":42,"title":"kid","restricted":true},
+				"sharingSettings":{"allowChannels":false,"allowTuners":0,"filterMovies":"contentRating=G%2CPG","filterTelevision":"contentRating=TV-Y%2CTV-G|label!=scary","filterMusic":""},
+				"libraries":[{"id":5,"key":1,"title":"Movies","type":"movie"}],"allLibraries":false},
+			{"id":2,"machineIdentifier":"abc","invitedId":7,"invited":{"id":7,"title":"friend"},"sharingSettings":{},"allLibraries":true}]`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL, PlexTVURL: server.URL}
+
+	restrictions, err := p.GetUserRestrictions(42)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(restrictions) != 1 {
+		t.Errorf("Expected: 1 shared server\n Got: %d", len(restrictions))
+		return
+	}
+
+	r := restrictions[0]
+
+	if r.MachineIdentifier != "abc" || r.AllowChannels || r.AllLibraries || len(r.Libraries) != 1 || r.Libraries[0].Title != "Movies" {
+		t.Errorf("Expected: the Movies library of abc without channels\n Got: %+v", r)
+	}
+
+	if strings.Join(r.Movies.ContentRatings, ",") != "G,PG" || strings.Join(r.Television.ExcludeLabels, ",") != "scary" {
+		t.Errorf("Expected: G and PG movies and tv shows without the scary label\n Got: %+v and %+v", r.Movies, r.Television)
+	}
+}
+
+func TestRemoveDevice(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/devices/1.xml":
+		case "/devices/2.xml":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL, PlexTVURL: server.URL}
+
+	if err := p.RemoveDevice(1); err != nil {
+		t.Error(err.Error())
+	}
+
+	if err := p.RemoveDevice(2); !errors.Is(err, ErrDeviceNotOwned) {
+		t.Errorf("Expected: %v\n Got: %v", ErrDeviceNotOwned, err)
+	}
+
+	if err := p.RemoveDevice(3); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected: %v\n Got: %v", ErrDeviceNotFound, err)
+	}
+}
+
+func TestSwitchHomeUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/home/users/2/switch" && r.URL.Query().Get("pin") == "1234":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `<user id="2" username="kid" authenticationToken="kidtoken"/>`)
+		case r.URL.Path == "/api/home/users/2/switch":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `<errors><error code="1041" message="Invalid PIN" status="401"/></errors>`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `<errors><error code="1001" message="User could not be authenticated" status="401"/></errors>`)
+		}
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL, PlexTVURL: server.URL}
+
+	if _, err := p.SwitchHomeUser(2, "1234"); err != nil {
+		t.Error(err.Error())
+	}
+
+	if _, err := p.SwitchHomeUser(2, ""); !errors.Is(err, ErrPINRequired) {
+		t.Errorf("Expected: %v\n Got: %v", ErrPINRequired, err)
+	}
+
+	if _, err := p.SwitchHomeUser(2, "0000"); !errors.Is(err, ErrInvalidPIN) {
+		t.Errorf("Expected: %v\n Got: %v", ErrInvalidPIN, err)
+	}
+
+	// an invalid token is not a pin error
+	if _, err := p.SwitchHomeUser(3, "1234"); err == nil || err.Error() != ErrorNotAuthorized {
+		t.Errorf("Expected: %s\n Got: %v", ErrorNotAuthorized, err)
 	}
 }
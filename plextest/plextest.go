@@ -0,0 +1,99 @@
+// Package plextest provides a mock Plex Media Server to unit test applications built on go-plex-client
+package plextest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/Arno500/go-plex-client"
+)
+
+// Token is the token of the Plex returned by NewMockServer
+const Token = "plextest-token"
+
+// MachineIdentifier is the machine identifier of the mock server
+const MachineIdentifier = "plextest-machine-id"
+
+// canned responses of the mock server
+const (
+	serverResponse = `{"MediaContainer":{"size":0,"friendlyName":"plextest","machineIdentifier":"` + MachineIdentifier + `","version":"1.32.0.0","platform":"Linux","transcoderActiveVideoSessions":0}}`
+
+	librarySectionsResponse = `{"MediaContainer":{"size":2,"Directory":[` +
+		`{"key":"1","type":"movie","title":"Movies","agent":"tv.plex.agents.movie","scanner":"Plex Movie","language":"en-US","uuid":"plextest-movies","Location":[{"id":1,"path":"/media/movies"}]},` +
+		`{"key":"2","type":"show","title":"TV Shows","agent":"tv.plex.agents.series","scanner":"Plex TV Series","language":"en-US","uuid":"plextest-shows","Location":[{"id":2,"path":"/media/shows"}]}]}}`
+
+	searchResponse = `{"MediaContainer":{"size":1,"Metadata":[` +
+		`{"ratingKey":"100","key":"/library/metadata/100","type":"movie","title":"Big Buck Bunny","year":2008,"duration":596000,"librarySectionID":1,"librarySectionTitle":"Movies"}]}}`
+
+	sessionsResponse = `{"MediaContainer":{"size":1,"Metadata":[` +
+		`{"ratingKey":"100","key":"/library/metadata/100","type":"movie","title":"Big Buck Bunny","duration":596000,"viewOffset":120000,"sessionKey":"1",` +
+		`"User":{"id":"1","title":"plextest"},"Player":{"machineIdentifier":"plextest-player","product":"Plex Web","state":"playing","title":"Chrome"},"Session":{"id":"plextest-session"}}]}}`
+)
+
+// MockServer is a Plex Media Server answering common endpoints with canned responses.
+// Use Handle to override an endpoint or to add one.
+type MockServer struct {
+	*httptest.Server
+	// Plex sends its requests, including plex.tv requests, to the mock server
+	Plex *plex.Plex
+
+	mu       sync.RWMutex
+	handlers map[string]http.HandlerFunc
+}
+
+// NewMockServer starts a mock server answering /, /library/sections, /search and /status/sessions.
+// Close it when done.
+func NewMockServer() *MockServer {
+	m := &MockServer{
+		handlers: map[string]http.HandlerFunc{
+			"/":                 JSON(http.StatusOK, serverResponse),
+			"/library/sections": JSON(http.StatusOK, librarySectionsResponse),
+			"/search":           JSON(http.StatusOK, searchResponse),
+			"/status/sessions":  JSON(http.StatusOK, sessionsResponse),
+		},
+	}
+
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+
+	// New only fails on an invalid url
+	m.Plex, _ = plex.New(m.URL, Token, plex.WithPlexTVURL(m.URL))
+
+	return m
+}
+
+// Handle answers requests to path (without the query) with handler, replacing any previous handler
+func (m *MockServer) Handle(path string, handler http.HandlerFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlers[path] = handler
+}
+
+// JSON returns a handler replying with status and the json body
+func JSON(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}
+}
+
+func (m *MockServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Plex-Token") != Token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	m.mu.RLock()
+	handler, ok := m.handlers[r.URL.Path]
+	m.mu.RUnlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	handler(w, r)
+}
@@ -0,0 +1,57 @@
+package plextest
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMockServer(t *testing.T) {
+	server := NewMockServer()
+	defer server.Close()
+
+	libraries, err := server.Plex.GetLibraries()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(libraries.MediaContainer.Directory) != 2 {
+		t.Errorf("Expected: 2 libraries\n Got: %d", len(libraries.MediaContainer.Directory))
+	}
+
+	results, err := server.Plex.Search("bunny")
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(results.MediaContainer.Metadata) != 1 || results.MediaContainer.Metadata[0].Title != "Big Buck Bunny" {
+		t.Errorf("Expected: Big Buck Bunny\n Got: %+v", results.MediaContainer.Metadata)
+	}
+
+	sessions, err := server.Plex.GetSessions()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(sessions.MediaContainer.Metadata) != 1 {
+		t.Errorf("Expected: 1 session\n Got: %d", len(sessions.MediaContainer.Metadata))
+	}
+
+	server.Handle("/status/sessions", JSON(http.StatusOK, `{"MediaContainer":{"size":0}}`))
+
+	sessions, err = server.Plex.GetSessions()
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if len(sessions.MediaContainer.Metadata) != 0 {
+		t.Errorf("Expected: the overridden response without sessions\n Got: %d", len(sessions.MediaContainer.Metadata))
+	}
+}
@@ -7,9 +7,12 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // ErrorResponse contains a code and an error message
@@ -38,8 +41,27 @@ type PinResponse struct {
 	}
 }
 
-// RequestPIN will retrieve a code (valid for 15 minutes) from plex.tv to link an app to your plex account
-func RequestPIN(requestHeaders headers) (PinResponse, error) {
+// pinClient returns the Plex sending the pin requests of RequestPIN and CheckPIN, configured with opts.
+// Pass the same WithRateLimit option to every call to share its limit, i.e. while polling CheckPIN
+func pinClient(h headers, opts []Option) *Plex {
+	p := &Plex{
+		ClientIdentifier: h.ClientIdentifier,
+		HTTPClient: http.Client{
+			Timeout: 3 * time.Second,
+		},
+		Headers: h,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// RequestPIN will retrieve a code (valid for 15 minutes) from plex.tv to link an app to your plex account.
+// opts such as WithRateLimit or WithPlexTVURL configure the request
+func RequestPIN(requestHeaders headers, opts ...Option) (PinResponse, error) {
 	endpoint := "/api/v2/pins.json"
 
 	// POST request and returns a 201 status code
@@ -58,7 +80,9 @@ func RequestPIN(requestHeaders headers) (PinResponse, error) {
 		requestHeaders = defaultHeaders()
 	}
 
-	resp, err := post(plexURL+endpoint, nil, requestHeaders)
+	p := pinClient(requestHeaders, opts)
+
+	resp, err := p.post(p.plexTVURL()+endpoint, nil, p.Headers)
 
 	if err != nil {
 		return pinInformation, err
@@ -79,8 +103,9 @@ func RequestPIN(requestHeaders headers) (PinResponse, error) {
 
 // CheckPIN will return information related to the pin such as the auth token if your code has been approved.
 // will return an error if code expired or still not linked
-// clientIdentifier must be the same when requesting a pin
-func CheckPIN(id int, clientIdentifier string) (PinResponse, error) {
+// clientIdentifier must be the same when requesting a pin. opts such as WithRateLimit or WithPlexTVURL
+// configure the request
+func CheckPIN(id int, clientIdentifier string, opts ...Option) (PinResponse, error) {
 	endpoint := "/api/v2/pins/"
 
 	endpoint = endpoint + strconv.Itoa(id) + ".json"
@@ -91,7 +116,9 @@ func CheckPIN(id int, clientIdentifier string) (PinResponse, error) {
 		headers.ClientIdentifier = clientIdentifier
 	}
 
-	resp, err := get(plexURL+endpoint, headers)
+	p := pinClient(headers, opts)
+
+	resp, err := p.get(p.plexTVURL()+endpoint, p.Headers)
 
 	if err != nil {
 		return PinResponse{}, err
@@ -132,7 +159,7 @@ func (p Plex) LinkAccount(code string) error {
 	headers.ContentType = "application/x-www-form-urlencoded"
 
 	// PUT request with 'code: <4-character-pin>' in the body
-	resp, err := p.put(plexURL+endpoint, []byte(body.Encode()), headers)
+	resp, err := p.put(p.plexTVURL()+endpoint, []byte(body.Encode()), headers)
 
 	if err != nil {
 		return err
@@ -166,7 +193,7 @@ func (p Plex) GetWebhooks() ([]string, error) {
 
 	endpoint := "/api/v2/user/webhooks"
 
-	resp, err := p.get(plexURL+endpoint, p.Headers)
+	resp, err := p.get(p.plexTVURL()+endpoint, p.Headers)
 
 	if err != nil {
 		return webhooks, err
@@ -220,7 +247,7 @@ func (p Plex) SetWebhooks(webhooks []string) error {
 
 	headers.ContentType = "application/x-www-form-urlencoded"
 
-	resp, err := p.post(plexURL+endpoint, []byte(body.Encode()), headers)
+	resp, err := p.post(p.plexTVURL()+endpoint, []byte(body.Encode()), headers)
 
 	if err != nil {
 		return err
@@ -241,7 +268,7 @@ func (p Plex) MyAccount() (UserPlexTV, error) {
 
 	var account UserPlexTV
 
-	resp, err := p.get(plexURL+endpoint, p.Headers)
+	resp, err := p.get(p.plexTVURL()+endpoint, p.Headers)
 
 	if err != nil {
 		return account, err
@@ -261,3 +288,511 @@ func (p Plex) MyAccount() (UserPlexTV, error) {
 
 	return account, err
 }
+
+// GetInvites returns the pending share invitations you have sent and the ones you have received
+func (p Plex) GetInvites() (Invites, error) {
+	var invites Invites
+
+	sent, err := p.getInvites("/api/v2/shared_servers/owned/pending")
+
+	if err != nil {
+		return invites, err
+	}
+
+	received, err := p.getInvites("/api/v2/shared_servers/received/pending")
+
+	if err != nil {
+		return invites, err
+	}
+
+	invites.Sent = sent
+	invites.Received = received
+
+	return invites, nil
+}
+
+// GetUserRestrictions returns the restrictions of a friend or a managed user on each of your servers shared with them.
+// It is empty when none of your servers is shared with the user
+func (p Plex) GetUserRestrictions(userID int) ([]UserRestrictions, error) {
+	shares, err := p.getInvites("/api/v2/shared_servers/owned/accepted")
+
+	if err != nil {
+		return nil, err
+	}
+
+	id := strconv.Itoa(userID)
+
+	var restrictions []UserRestrictions
+
+	for _, share := range shares {
+		if share.InvitedID.String() != id && share.Invited.ID.String() != id {
+			continue
+		}
+
+		r := UserRestrictions{
+			MachineIdentifier: share.MachineIdentifier,
+			SharingSettings:   share.SharingSettings,
+			AllLibraries:      share.AllLibraries,
+			Libraries:         share.Libraries,
+		}
+
+		if r.Movies, err = ParseSharingFilter(share.SharingSettings.FilterMovies); err != nil {
+			return nil, err
+		}
+
+		if r.Television, err = ParseSharingFilter(share.SharingSettings.FilterTelevision); err != nil {
+			return nil, err
+		}
+
+		if r.Music, err = ParseSharingFilter(share.SharingSettings.FilterMusic); err != nil {
+			return nil, err
+		}
+
+		restrictions = append(restrictions, r)
+	}
+
+	return restrictions, nil
+}
+
+func (p Plex) getInvites(endpoint string) ([]SharedServer, error) {
+	var invites []SharedServer
+
+	resp, err := p.get(p.plexTVURL()+endpoint, p.Headers)
+
+	if err != nil {
+		return invites, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return invites, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return invites, errors.New(resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&invites); err != nil {
+		return invites, err
+	}
+
+	return invites, nil
+}
+
+// AcceptInvite accepts a share invitation you received. The returned SharedServer
+// contains the machine identifier of the server you now have access to
+func (p Plex) AcceptInvite(inviteID string) (SharedServer, error) {
+	var result SharedServer
+
+	if inviteID == "" {
+		return result, errors.New("an invite id is required")
+	}
+
+	resp, err := p.post(p.plexTVURL()+"/api/v2/shared_servers/"+inviteID+"/accept", nil, p.Headers)
+
+	if err != nil {
+		return result, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return result, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return result, errors.New(resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// DeclineInvite declines a share invitation you received
+func (p Plex) DeclineInvite(inviteID string) error {
+	if inviteID == "" {
+		return errors.New("an invite id is required")
+	}
+
+	resp, err := p.delete(p.plexTVURL()+"/api/v2/shared_servers/"+inviteID, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return errors.New(resp.Status)
+	}
+
+	return nil
+}
+
+// GetHomeUsers returns the users of your plex home, including managed users
+func (p Plex) GetHomeUsers() ([]HomeUser, error) {
+	var result homeUsersResponse
+
+	newHeaders := p.Headers
+
+	newHeaders.Accept = "application/xml"
+
+	resp, err := p.get(p.plexTVURL()+"/api/home/users", newHeaders)
+
+	if err != nil {
+		return result.User, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return result.User, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return result.User, errors.New(resp.Status)
+	}
+
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result.User, err
+	}
+
+	return result.User, nil
+}
+
+// CreateHomeUser creates a managed user in your plex home. Managed users do not need
+// a plex.tv account and are restricted by default
+func (p Plex) CreateHomeUser(name string) (HomeUser, error) {
+	var user HomeUser
+
+	if name == "" {
+		return user, errors.New("a name is required")
+	}
+
+	newHeaders := p.Headers
+
+	newHeaders.Accept = "application/xml"
+
+	query := p.plexTVURL() + "/api/home/users?title=" + url.QueryEscape(name)
+
+	resp, err := p.post(query, nil, newHeaders)
+
+	if err != nil {
+		return user, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return user, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return user, errors.New(resp.Status)
+	}
+
+	if err := xml.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// SetHomeUserPIN sets the 4 digit pin a home user has to enter to switch to their profile.
+// An empty pin removes the pin
+func (p Plex) SetHomeUserPIN(userID int, pin string) error {
+	if pin != "" {
+		if _, err := strconv.Atoi(pin); err != nil || len(pin) != 4 {
+			return errors.New("pin must be 4 digits")
+		}
+	}
+
+	query := fmt.Sprintf("%s/api/home/users/%d?pin=%s", p.plexTVURL(), userID, pin)
+
+	resp, err := p.put(query, nil, p.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+
+	return nil
+}
+
+// SwitchHomeUser switches to another user of your plex home. Use the AuthToken of the returned user for subsequent requests.
+// pin is only required if the user is protected. Returns ErrPINRequired or ErrInvalidPIN if the pin was refused
+func (p Plex) SwitchHomeUser(userID int, pin string) (UserPlexTV, error) {
+	var user UserPlexTV
+
+	query := fmt.Sprintf("%s/api/home/users/%d/switch", p.plexTVURL(), userID)
+
+	if pin != "" {
+		query += "?pin=" + url.QueryEscape(pin)
+	}
+
+	newHeaders := p.Headers
+
+	newHeaders.Accept = "application/xml"
+
+	resp, err := p.post(query, nil, newHeaders)
+
+	if err != nil {
+		return user, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		body, _ := ioutil.ReadAll(resp.Body)
+
+		if !isPINError(body) {
+			return user, errors.New(ErrorNotAuthorized)
+		}
+
+		if pin == "" {
+			return user, ErrPINRequired
+		}
+
+		return user, ErrInvalidPIN
+	} else if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return user, errors.New(resp.Status)
+	}
+
+	if err := xml.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// pinErrorCode is the code of plex.tv errors caused by a missing or wrong pin
+const pinErrorCode = 1041
+
+// isPINError reports whether the body of a plex.tv error blames the pin rather than the token
+func isPINError(body []byte) bool {
+	var messages []string
+
+	var errs plexTVErrorsResponse
+
+	if err := xml.Unmarshal(body, &errs); err == nil {
+		for _, e := range errs.Error {
+			if e.Code == pinErrorCode {
+				return true
+			}
+
+			messages = append(messages, e.Message)
+		}
+	}
+
+	var result resultResponse
+
+	if err := xml.Unmarshal(body, &result); err == nil {
+		if result.Code == pinErrorCode {
+			return true
+		}
+
+		messages = append(messages, result.Status)
+	}
+
+	for _, message := range messages {
+		if strings.Contains(strings.ToLower(message), "pin") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// base urls of plex's online catalog, replaced by PlexTVURL when it is set
+const (
+	defaultDiscoverURL         = "https://discover.provider.plex.tv"
+	defaultMetadataProviderURL = "https://metadata.provider.plex.tv"
+)
+
+// discoverURL returns the base url of discover searches
+func (p Plex) discoverURL() string {
+	if p.PlexTVURL != "" {
+		return p.plexTVURL()
+	}
+
+	return defaultDiscoverURL
+}
+
+// metadataProviderURL returns the base url of discover metadata requests
+func (p Plex) metadataProviderURL() string {
+	if p.PlexTVURL != "" {
+		return p.plexTVURL()
+	}
+
+	return defaultMetadataProviderURL
+}
+
+// DiscoverSearch searches plex's online catalog (movies and tv shows) for media that may not be in your libraries
+func (p Plex) DiscoverSearch(query string) (DiscoverMetadataResponse, error) {
+	var result DiscoverMetadataResponse
+
+	if query == "" {
+		return result, fmt.Errorf(ErrorCommon, ErrorTitleRequired)
+	}
+
+	endpoint := fmt.Sprintf("%s/library/search?query=%s&searchTypes=movies,tv&searchProviders=discover&limit=30", p.discoverURL(), url.QueryEscape(query))
+
+	resp, err := p.get(endpoint, p.Headers)
+
+	if err != nil {
+		return result, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return result, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return result, errors.New(resp.Status)
+	}
+
+	var searchResult discoverSearchResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&searchResult); err != nil {
+		return result, err
+	}
+
+	for _, provider := range searchResult.MediaContainer.SearchResults {
+		for _, item := range provider.SearchResult {
+			result.MediaContainer.Metadata = append(result.MediaContainer.Metadata, item.Metadata)
+		}
+	}
+
+	result.MediaContainer.Size = len(result.MediaContainer.Metadata)
+	result.MediaContainer.TotalSize = result.MediaContainer.Size
+
+	return result, nil
+}
+
+// GetDiscoverMetadata returns plex's online metadata of a piece of media via its discover rating key
+func (p Plex) GetDiscoverMetadata(ratingKey string) (DiscoverMetadataResponse, error) {
+	var result DiscoverMetadataResponse
+
+	if ratingKey == "" {
+		return result, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	resp, err := p.get(p.metadataProviderURL()+"/library/metadata/"+ratingKey, p.Headers)
+
+	if err != nil {
+		return result, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return result, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return result, errors.New(resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// CreateSyncItem syncs (downloads) a piece of media of your server to the device with clientIdentifier
+// for offline playback, transcoded to quality (i.e. SyncQuality720p). Syncing a show or a season syncs its episodes.
+func (p Plex) CreateSyncItem(ratingKey, clientIdentifier string, quality SyncQuality) (SyncItem, error) {
+	key, err := strconv.Atoi(ratingKey)
+
+	if err != nil {
+		return SyncItem{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	if clientIdentifier == "" {
+		return SyncItem{}, errors.New("a client identifier is required")
+	}
+
+	metadata, err := p.GetMetadata(ratingKey)
+
+	if err != nil {
+		return SyncItem{}, err
+	}
+
+	if len(metadata.MediaContainer.Metadata) == 0 {
+		return SyncItem{}, fmt.Errorf("no media with the key %s", ratingKey)
+	}
+
+	media := metadata.MediaContainer.Metadata[0]
+
+	machineID, err := p.serverMachineID()
+
+	if err != nil {
+		return SyncItem{}, err
+	}
+
+	uri, err := p.libraryURI([]int{key})
+
+	if err != nil {
+		return SyncItem{}, err
+	}
+
+	rootTitle := media.Title
+
+	if media.GrandparentTitle != "" {
+		rootTitle = media.GrandparentTitle
+	} else if media.ParentTitle != "" {
+		rootTitle = media.ParentTitle
+	}
+
+	body := url.Values{}
+	body.Set("SyncItem[title]", media.Title)
+	body.Set("SyncItem[rootTitle]", rootTitle)
+	body.Set("SyncItem[metadataType]", GetMediaTypeID(media.Type))
+	body.Set("SyncItem[machineIdentifier]", machineID)
+	body.Set("SyncItem[contentType]", "video")
+	body.Set("SyncItem[Policy][scope]", "all")
+	body.Set("SyncItem[Policy][unwatched]", "0")
+	body.Set("SyncItem[Policy][value]", "0")
+	body.Set("SyncItem[Location][uri]", uri)
+	body.Set("SyncItem[MediaSettings][audioBoost]", "100")
+	body.Set("SyncItem[MediaSettings][subtitleSize]", "100")
+	body.Set("SyncItem[MediaSettings][maxVideoBitrate]", "")
+	body.Set("SyncItem[MediaSettings][videoQuality]", "")
+	body.Set("SyncItem[MediaSettings][videoResolution]", quality.VideoResolution)
+
+	if quality != SyncQualityOriginal {
+		body.Set("SyncItem[MediaSettings][maxVideoBitrate]", strconv.Itoa(quality.MaxVideoBitrate))
+		body.Set("SyncItem[MediaSettings][videoQuality]", strconv.Itoa(quality.VideoQuality))
+	}
+
+	query := fmt.Sprintf("%s/devices/%s/sync_items?%s", p.plexTVURL(), url.PathEscape(clientIdentifier), body.Encode())
+
+	newHeaders := p.Headers
+	newHeaders.Accept = "application/xml"
+
+	resp, err := p.post(query, nil, newHeaders)
+
+	if err != nil {
+		return SyncItem{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return SyncItem{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return SyncItem{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	var result SyncItem
+
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return SyncItem{}, err
+	}
+
+	return result, nil
+}
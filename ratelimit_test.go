@@ -0,0 +1,83 @@
+package plex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(20, 2)
+
+	start := time.Now()
+
+	// the burst is available immediately, the next 2 requests wait 50ms each
+	for ii := 0; ii < 4; ii++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Error(err.Error())
+			return
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected: at least %s\n Got: %s", 90*time.Millisecond, elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := l.wait(ctx); err != context.Canceled {
+		t.Errorf("Expected: %v\n Got: %v", context.Canceled, err)
+	}
+}
+
+func TestWithRateLimitPIN(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		if r.Header.Get("X-Plex-Token") != "" {
+			t.Errorf("Expected: no token\n Got: %s", r.Header.Get("X-Plex-Token"))
+		}
+
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":1,"code":"ABCD"}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"id":1,"code":"ABCD","authToken":"abc123"}`)
+	}))
+	defer server.Close()
+
+	// the same option shares its limit between the pin requests
+	limit := WithRateLimit(0.001, 1)
+
+	pin, err := RequestPIN(defaultHeaders(), limit, WithPlexTVURL(server.URL))
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := CheckPIN(pin.ID, "", limit, WithPlexTVURL(server.URL), WithRequestContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected: %v\n Got: %v", context.DeadlineExceeded, err)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected: %d request\n Got: %d", 1, n)
+	}
+
+	if _, err := CheckPIN(pin.ID, "", WithPlexTVURL(server.URL)); err != nil {
+		t.Error(err.Error())
+	}
+}
@@ -1,6 +1,14 @@
 package plex
 
-import "regexp"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+)
 
 // SearchPlex searches just like Search, but omits the last 4 results which are not relevant
 func (p *Plex) SearchPlex(title string) (SearchResults, error) {
@@ -76,3 +84,56 @@ func (p *Plex) ExtractKeyFromRatingKeyRegex(key string) string {
 
 	return result[0]
 }
+
+// HubSearch searches all of your libraries via the /hubs/search endpoint. Results are grouped
+// into hubs by type (movie, show, episode, actor, etc). limit is the max amount of results per hub, 0 uses plex's default
+func (p *Plex) HubSearch(query string, limit int) (Hubs, error) {
+	if query == "" {
+		return Hubs{}, fmt.Errorf(ErrorCommon, ErrorTitleRequired)
+	}
+
+	endpoint := fmt.Sprintf("%s/hubs/search?query=%s", p.URL, url.QueryEscape(query))
+
+	if limit > 0 {
+		endpoint += fmt.Sprintf("&limit=%d", limit)
+	}
+
+	resp, err := p.get(endpoint, p.Headers)
+
+	if err != nil {
+		return Hubs{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return Hubs{}, errors.New(ErrorNotAuthorized)
+	} else if resp.StatusCode != http.StatusOK {
+		return Hubs{}, fmt.Errorf(ErrorServerReplied, resp.StatusCode)
+	}
+
+	var results Hubs
+
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return Hubs{}, err
+	}
+
+	return results, nil
+}
+
+// SearchInSection searches a single library section for media of mediaType (i.e. MediaTypeMovie) whose title matches query
+func (p *Plex) SearchInSection(sectionKey, query string, mediaType MediaType) (SearchResults, error) {
+	if sectionKey == "" {
+		return SearchResults{}, fmt.Errorf(ErrorCommon, ErrorKeyIsRequired)
+	}
+
+	if query == "" {
+		return SearchResults{}, fmt.Errorf(ErrorCommon, ErrorTitleRequired)
+	}
+
+	params := url.Values{}
+	params.Set("type", strconv.Itoa(int(mediaType)))
+	params.Set("title", query)
+
+	return p.GetLibraryContent(sectionKey, "?"+params.Encode())
+}
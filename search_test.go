@@ -1,6 +1,11 @@
 package plex
 
-import "testing"
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
 
 func TestExtractKeyFromRatingKey(t *testing.T) {
 	keys := [][]string{
@@ -100,3 +105,62 @@ func BenchmarkExtractKeyFromRatingKeyRegex(b *testing.B) {
 		p.ExtractKeyFromRatingKeyRegex(ratingKey)
 	}
 }
+
+func TestHubSearch(t *testing.T) {
+	testData := `{"MediaContainer":{"size":2,"Hub":[
+		{"title":"Movies","type":"movie","hubIdentifier":"movie","size":1,"more":false,"Metadata":[{"ratingKey":"1","title":"Alien","type":"movie","year":1979}]},
+		{"title":"Actors","type":"actor","hubIdentifier":"actor","size":1,"more":false,"Directory":[{"key":"/library/people/2","title":"Sigourney Weaver","type":"tag"}]}
+	]}}`
+
+	_, _plex := newTestServer(200, testData)
+
+	results, err := _plex.HubSearch("alien", 5)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	hubs := results.MediaContainer.Hub
+
+	if len(hubs) != 2 {
+		t.Errorf("Expected: 2 hubs\n Got: %d", len(hubs))
+		return
+	}
+
+	if hubs[0].HubIdentifier != "movie" || hubs[0].Metadata[0].Title != "Alien" {
+		t.Errorf("unexpected movie hub: %+v", hubs[0])
+	}
+
+	if hubs[1].Type != "actor" || len(hubs[1].Directory) != 1 {
+		t.Errorf("unexpected actor hub: %+v", hubs[1])
+	}
+}
+
+func TestSearchInSection(t *testing.T) {
+	var query string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RequestURI()
+
+		fmt.Fprintln(w, `{"MediaContainer":{"size":1,"Metadata":[{"ratingKey":"1","title":"Alien","type":"movie"}]}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	results, err := p.SearchInSection("1", "alien", MediaTypeMovie)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if expected := "/library/sections/1/all?title=alien&type=1"; query != expected {
+		t.Errorf("Expected: %s\n Got: %s", expected, query)
+	}
+
+	if len(results.MediaContainer.Metadata) != 1 {
+		t.Errorf("Expected: 1 result\n Got: %d", len(results.MediaContainer.Metadata))
+	}
+}
@@ -0,0 +1,155 @@
+package plex
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ServerGroup runs the same request against several servers concurrently, i.e. to list the sessions
+// of all of your servers. A server failing does not stop the requests to the other servers.
+type ServerGroup struct {
+	Servers []*Plex
+	// Concurrency is the maximum number of servers requested at once, every server when 0
+	Concurrency int
+
+	mu         sync.Mutex
+	machineIDs map[*Plex]string
+}
+
+// NewServerGroup creates a group of servers
+func NewServerGroup(servers ...*Plex) *ServerGroup {
+	return &ServerGroup{Servers: servers}
+}
+
+// ServerError is the error of a single server of a ServerGroup
+type ServerError struct {
+	Server *Plex
+	// MachineIdentifier is empty when the server could not be reached
+	MachineIdentifier string
+	Err               error
+}
+
+func (e ServerError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Server.URL, e.Err)
+}
+
+// Unwrap returns the error of the server
+func (e ServerError) Unwrap() error {
+	return e.Err
+}
+
+// ServerSession is a session of one of the servers of a ServerGroup
+type ServerSession struct {
+	Server            *Plex
+	MachineIdentifier string
+	MetadataV1
+}
+
+// Each calls fn with every server and its machine identifier, at most Concurrency at once,
+// and returns the errors of the servers fn failed for
+func (g *ServerGroup) Each(fn func(p *Plex, machineID string) error) []ServerError {
+	return g.each(func(_ int, p *Plex, machineID string) error {
+		return fn(p, machineID)
+	})
+}
+
+// Sessions returns the sessions of every server, in the order of Servers, and the errors of the servers
+// whose sessions could not be fetched
+func (g *ServerGroup) Sessions() ([]ServerSession, []ServerError) {
+	results := make([][]ServerSession, len(g.Servers))
+
+	errs := g.each(func(i int, p *Plex, machineID string) error {
+		sessions, err := p.GetSessions()
+
+		if err != nil {
+			return err
+		}
+
+		for _, session := range sessions.MediaContainer.Metadata {
+			results[i] = append(results[i], ServerSession{Server: p, MachineIdentifier: machineID, MetadataV1: session})
+		}
+
+		return nil
+	})
+
+	var sessions []ServerSession
+
+	for _, result := range results {
+		sessions = append(sessions, result...)
+	}
+
+	return sessions, errs
+}
+
+func (g *ServerGroup) each(fn func(i int, p *Plex, machineID string) error) []ServerError {
+	concurrency := g.Concurrency
+
+	if concurrency <= 0 || concurrency > len(g.Servers) {
+		concurrency = len(g.Servers)
+	}
+
+	errs := make([]*ServerError, len(g.Servers))
+	slots := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+
+	for i, p := range g.Servers {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(i int, p *Plex) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			machineID, err := g.machineID(p)
+
+			if err == nil {
+				err = fn(i, p, machineID)
+			}
+
+			if err != nil {
+				errs[i] = &ServerError{Server: p, MachineIdentifier: machineID, Err: err}
+			}
+		}(i, p)
+	}
+
+	wg.Wait()
+
+	var serverErrs []ServerError
+
+	for _, err := range errs {
+		if err != nil {
+			serverErrs = append(serverErrs, *err)
+		}
+	}
+
+	return serverErrs
+}
+
+// machineID returns the machine identifier of a server of the group, fetched once
+func (g *ServerGroup) machineID(p *Plex) (string, error) {
+	g.mu.Lock()
+	machineID, ok := g.machineIDs[p]
+	g.mu.Unlock()
+
+	if ok {
+		return machineID, nil
+	}
+
+	machineID, err := p.serverMachineID()
+
+	if err != nil {
+		return "", err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.machineIDs == nil {
+		g.machineIDs = map[*Plex]string{}
+	}
+
+	g.machineIDs[p] = machineID
+
+	return machineID, nil
+}
@@ -0,0 +1,60 @@
+package plex
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerGroupSessions(t *testing.T) {
+	newServer := func(machineID string, sessions int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/":
+				fmt.Fprintf(w, `{"MediaContainer":{"machineIdentifier":"%s"}}`, machineID)
+			case "/status/sessions":
+				fmt.Fprint(w, `{"MediaContainer":{"Metadata":[`)
+
+				for i := 0; i < sessions; i++ {
+					if i > 0 {
+						fmt.Fprint(w, ",")
+					}
+
+					fmt.Fprintf(w, `{"sessionKey":"%d"}`, i)
+				}
+
+				fmt.Fprint(w, `]}}`)
+			}
+		}))
+	}
+
+	first := newServer("first", 2)
+	defer first.Close()
+
+	second := newServer("second", 1)
+	defer second.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer failing.Close()
+
+	group := NewServerGroup(&Plex{URL: first.URL}, &Plex{URL: failing.URL}, &Plex{URL: second.URL})
+	group.Concurrency = 2
+
+	sessions, errs := group.Sessions()
+
+	if len(sessions) != 3 {
+		t.Errorf("Expected: 3 sessions\n Got: %d", len(sessions))
+		return
+	}
+
+	if sessions[0].MachineIdentifier != "first" || sessions[1].MachineIdentifier != "first" || sessions[2].MachineIdentifier != "second" {
+		t.Errorf("Expected: 2 sessions of first and 1 of second\n Got: %+v", sessions)
+	}
+
+	if len(errs) != 1 || errs[0].Server.URL != failing.URL || errs[0].Err.Error() != ErrorNotAuthorized {
+		t.Errorf("Expected: an error for the failing server\n Got: %v", errs)
+	}
+}
@@ -0,0 +1,98 @@
+package plex
+
+import (
+	"sync"
+	"time"
+)
+
+// SessionCache looks up the session (media, user and player) of playing notifications. Sessions are
+// fetched from the server at most once per TTL, as playing notifications are sent every few seconds
+// for every session.
+type SessionCache struct {
+	plex *Plex
+
+	// TTL is how long fetched sessions are reused
+	TTL time.Duration
+
+	mu        sync.Mutex
+	sessions  map[string]MetadataV1
+	fetchedAt time.Time
+	fetching  *pendingFetch
+}
+
+// pendingFetch is a fetch in flight, shared by concurrent lookups of a cache
+type pendingFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewSessionCache creates a cache reusing fetched sessions for ttl
+func (p *Plex) NewSessionCache(ttl time.Duration) *SessionCache {
+	return &SessionCache{
+		plex: p,
+		TTL:  ttl,
+	}
+}
+
+// Lookup returns the session of a playing notification. Sessions are fetched again once the cache
+// expired, so a new session may take up to TTL to be found. It returns ErrSessionNotFound when the
+// server had no session with the key, i.e. when it already ended.
+func (s *SessionCache) Lookup(n PlaySessionStateNotification) (MetadataV1, error) {
+	if err := s.refresh(); err != nil {
+		return MetadataV1{}, err
+	}
+
+	s.mu.Lock()
+	session, ok := s.sessions[n.SessionKey]
+	s.mu.Unlock()
+
+	if !ok {
+		return MetadataV1{}, ErrSessionNotFound
+	}
+
+	return session, nil
+}
+
+// refresh fetches the sessions when the cache expired. Concurrent lookups wait for the same fetch
+// rather than each sending a request.
+func (s *SessionCache) refresh() error {
+	s.mu.Lock()
+
+	if s.sessions != nil && time.Since(s.fetchedAt) < s.TTL {
+		s.mu.Unlock()
+		return nil
+	}
+
+	if f := s.fetching; f != nil {
+		s.mu.Unlock()
+		<-f.done
+
+		return f.err
+	}
+
+	f := &pendingFetch{done: make(chan struct{})}
+	s.fetching = f
+	s.mu.Unlock()
+
+	result, err := s.plex.GetSessions()
+
+	s.mu.Lock()
+
+	if err == nil {
+		s.sessions = make(map[string]MetadataV1, len(result.MediaContainer.Metadata))
+
+		for _, session := range result.MediaContainer.Metadata {
+			s.sessions[session.SessionKey] = session
+		}
+
+		s.fetchedAt = time.Now()
+	}
+
+	s.fetching = nil
+	s.mu.Unlock()
+
+	f.err = err
+	close(f.done)
+
+	return err
+}
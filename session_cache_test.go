@@ -0,0 +1,99 @@
+package plex
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSessionCache(t *testing.T) {
+	var fetches int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+
+		fmt.Fprint(w, `{"MediaContainer":{"size":1,"Metadata":[{"sessionKey":"1","title":"Big Buck Bunny","User":{"title":"brw"},"Player":{"title":"Chrome"}}]}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	cache := p.NewSessionCache(time.Minute)
+
+	for ii := 0; ii < 3; ii++ {
+		session, err := cache.Lookup(PlaySessionStateNotification{SessionKey: "1"})
+
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if session.Title != "Big Buck Bunny" || session.User.Title != "brw" || session.Player.Title != "Chrome" {
+			t.Errorf("Expected: Big Buck Bunny played by brw on Chrome\n Got: %+v", session)
+		}
+	}
+
+	if fetches != 1 {
+		t.Errorf("Expected: sessions to be fetched once\n Got: %d", fetches)
+	}
+
+	if _, err := cache.Lookup(PlaySessionStateNotification{SessionKey: "2"}); err != ErrSessionNotFound {
+		t.Errorf("Expected: %v\n Got: %v", ErrSessionNotFound, err)
+	}
+
+	if fetches != 1 {
+		t.Errorf("Expected: an unknown session to be looked up in the cache\n Got: %d fetches", fetches)
+	}
+}
+
+func TestSessionCacheExpires(t *testing.T) {
+	var fetches int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+
+		// slow enough for concurrent lookups to wait for the same fetch
+		time.Sleep(20 * time.Millisecond)
+
+		fmt.Fprint(w, `{"MediaContainer":{"size":1,"Metadata":[{"sessionKey":"1","title":"Big Buck Bunny"}]}}`)
+	}))
+	defer server.Close()
+
+	p := &Plex{URL: server.URL}
+
+	cache := p.NewSessionCache(50 * time.Millisecond)
+
+	var wg sync.WaitGroup
+
+	for ii := 0; ii < 10; ii++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := cache.Lookup(PlaySessionStateNotification{SessionKey: "2"}); err != ErrSessionNotFound {
+				t.Errorf("Expected: %v\n Got: %v", ErrSessionNotFound, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("Expected: concurrent lookups to share a fetch\n Got: %d fetches", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if _, err := cache.Lookup(PlaySessionStateNotification{SessionKey: "1"}); err != nil {
+		t.Error(err.Error())
+	}
+
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Errorf("Expected: the expired cache to be fetched again\n Got: %d fetches", n)
+	}
+}
@@ -33,6 +33,20 @@ func (t Timestamp) Unix() int64 {
 	return time.Time(t).Unix()
 }
 
+// IsZero reports whether t is missing from the JSON
+func (t Timestamp) IsZero() bool {
+	return time.Time(t).IsZero()
+}
+
+// epoch returns t as epoch seconds, and 0 when t is missing
+func (t Timestamp) epoch() int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
 // Time returns the JSON time as a time.Time instance in UTC
 func (t Timestamp) Time() time.Time {
 	return time.Time(t).UTC()
@@ -42,3 +56,152 @@ func (t Timestamp) Time() time.Time {
 func (t Timestamp) String() string {
 	return t.Time().String()
 }
+
+// plex.tv formats of dates that are not epoch seconds
+var plexTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+}
+
+// parsePlexTime parses a date plex sent either as epoch seconds or as a date string.
+// It returns the zero time when value is empty or can not be parsed.
+func parsePlexTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+
+	if epoch, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return epochToTime(epoch)
+	}
+
+	for _, layout := range plexTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC()
+		}
+	}
+
+	return time.Time{}
+}
+
+// epochToTime converts epoch seconds to a time in UTC, and 0 to the zero time
+func epochToTime(epoch int64) time.Time {
+	if epoch == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(epoch, 0).UTC()
+}
+
+// Created returns when the device was registered
+func (d PMSDevices) Created() time.Time {
+	return parsePlexTime(d.CreatedAt)
+}
+
+// LastSeen returns when the device last connected to plex.tv
+func (d PMSDevices) LastSeen() time.Time {
+	return parsePlexTime(d.LastSeenAt)
+}
+
+// LastSeen returns when the device last connected to plex.tv
+func (d DevicesResponse) LastSeen() time.Time {
+	return parsePlexTime(d.LastSeenAt)
+}
+
+// LastSeen returns when the friend's shared server was last seen
+func (f Friends) LastSeen() time.Time {
+	return parsePlexTime(f.Server.LastSeenAt)
+}
+
+// Created returns when the library was created
+func (d Directory) Created() time.Time {
+	return epochToTime(int64(d.CreatedAt))
+}
+
+// Updated returns when the library was last updated
+func (d Directory) Updated() time.Time {
+	return epochToTime(int64(d.UpdatedAt))
+}
+
+// Added returns when the media was added to the library
+func (m Metadata) Added() time.Time {
+	return epochToTime(int64(m.AddedAt))
+}
+
+// Updated returns when the media was last updated
+func (m Metadata) Updated() time.Time {
+	return epochToTime(int64(m.UpdatedAt))
+}
+
+// LastViewed returns when the media was last watched
+func (m Metadata) LastViewed() time.Time {
+	return epochToTime(int64(m.LastViewedAt))
+}
+
+// Added returns when the media was added to the library
+func (m MetadataV1) Added() time.Time {
+	return epochToTime(m.AddedAt)
+}
+
+// Updated returns when the media was last updated
+func (m MetadataV1) Updated() time.Time {
+	return epochToTime(m.UpdatedAt.epoch())
+}
+
+// LastViewed returns when the media was last watched
+func (m MetadataV1) LastViewed() time.Time {
+	return epochToTime(m.LastViewedAt.epoch())
+}
+
+// Runtime returns the duration of the media. It is not named Duration as that is the field in milliseconds.
+func (m Metadata) Runtime() time.Duration {
+	return time.Duration(m.Duration) * time.Millisecond
+}
+
+// ViewProgress returns how much of the media was watched in percent, according to ViewOffset
+func (m Metadata) ViewProgress() float64 {
+	return viewProgress(int64(m.ViewOffset), int64(m.Duration))
+}
+
+// Runtime returns the duration of the media. It is not named Duration as that is the field in milliseconds.
+func (m MetadataV1) Runtime() time.Duration {
+	return time.Duration(m.Duration) * time.Millisecond
+}
+
+// ViewProgress returns how much of the media was watched in percent, according to ViewOffset
+func (m MetadataV1) ViewProgress() float64 {
+	return viewProgress(m.ViewOffset, m.Duration)
+}
+
+// Runtime returns the duration of the media. It is not named Duration as that is the field in milliseconds.
+func (m Media) Runtime() time.Duration {
+	return time.Duration(m.Duration) * time.Millisecond
+}
+
+// Start returns the offset the marker starts at
+func (m Marker) Start() time.Duration {
+	return time.Duration(m.StartTimeOffset) * time.Millisecond
+}
+
+// End returns the offset the marker ends at
+func (m Marker) End() time.Duration {
+	return time.Duration(m.EndTimeOffset) * time.Millisecond
+}
+
+// Start returns the offset the chapter starts at
+func (c Chapter) Start() time.Duration {
+	return time.Duration(c.StartTimeOffset) * time.Millisecond
+}
+
+// End returns the offset the chapter ends at
+func (c Chapter) End() time.Duration {
+	return time.Duration(c.EndTimeOffset) * time.Millisecond
+}
+
+func viewProgress(viewOffset, duration int64) float64 {
+	if duration <= 0 {
+		return 0
+	}
+
+	return float64(viewOffset) / float64(duration) * 100
+}
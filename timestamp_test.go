@@ -0,0 +1,42 @@
+package plex
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePlexTime(t *testing.T) {
+	expected := time.Date(2021, 4, 23, 20, 15, 34, 0, time.UTC)
+
+	for _, value := range []string{"1619208934", "2021-04-23T20:15:34Z", "2021-04-23 20:15:34 UTC", "2021-04-23 20:15:34"} {
+		if got := parsePlexTime(value); !got.Equal(expected) {
+			t.Errorf("%s: Expected: %s\n Got: %s", value, expected, got)
+		}
+	}
+
+	for _, value := range []string{"", "0", "yesterday"} {
+		if got := parsePlexTime(value); !got.IsZero() {
+			t.Errorf("%s: Expected: zero time\n Got: %s", value, got)
+		}
+	}
+
+	if got := (PMSDevices{LastSeenAt: "1619208934"}).LastSeen(); !got.Equal(expected) {
+		t.Errorf("Expected: %s\n Got: %s", expected, got)
+	}
+}
+
+func TestRuntime(t *testing.T) {
+	m := Metadata{Duration: 6120000, ViewOffset: 3060000}
+
+	if got := m.Runtime(); got != time.Hour+42*time.Minute {
+		t.Errorf("Expected: %s\n Got: %s", time.Hour+42*time.Minute, got)
+	}
+
+	if got := m.ViewProgress(); got != 50 {
+		t.Errorf("Expected: 50\n Got: %f", got)
+	}
+
+	if got := (Metadata{}).ViewProgress(); got != 0 {
+		t.Errorf("Expected: 0\n Got: %f", got)
+	}
+}
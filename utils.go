@@ -2,6 +2,8 @@ package plex
 
 import (
 	"bytes"
+	"context"
+	"io/ioutil"
 	"net/http"
 	"time"
 )
@@ -27,7 +29,7 @@ import (
 func (p *Plex) grab(query string, h headers) (*http.Response, error) {
 	client := p.DownloadClient
 
-	req, reqErr := http.NewRequest("GET", query, nil)
+	req, reqErr := http.NewRequestWithContext(p.requestContext(), "GET", query, nil)
 
 	if reqErr != nil {
 		return &http.Response{}, reqErr
@@ -43,14 +45,26 @@ func (p *Plex) grab(query string, h headers) (*http.Response, error) {
 	req.Header.Add("X-Plex-Device", h.Device)
 	// req.Header.Add("X-Plex-Container-Size", h.ContainerSize)
 	// req.Header.Add("X-Plex-Container-Start", h.ContainerStart)
-	req.Header.Add("X-Plex-Token", p.Token)
+	if p.Token != "" {
+		req.Header.Add("X-Plex-Token", p.Token)
+	}
 
 	// optional headers
 	if h.TargetClientIdentifier != "" {
 		req.Header.Add("X-Plex-Target-Identifier", h.TargetClientIdentifier)
+		req.Header.Add("X-Plex-Target-Client-Identifier", h.TargetClientIdentifier)
+	}
+
+	if h.Language != "" {
+		req.Header.Add("X-Plex-Language", h.Language)
+		req.Header.Add("Accept-Language", h.Language)
+	}
+
+	if h.Range != "" {
+		req.Header.Add("Range", h.Range)
 	}
 
-	resp, err := client.Do(req)
+	resp, err := p.do(&client, req)
 
 	if err != nil {
 		return &http.Response{}, err
@@ -62,76 +76,94 @@ func (p *Plex) grab(query string, h headers) (*http.Response, error) {
 func (p *Plex) get(query string, h headers) (*http.Response, error) {
 	client := p.HTTPClient
 
-	req, reqErr := http.NewRequest("GET", query, nil)
+	req, reqErr := p.newGetRequest(query, h)
 
 	if reqErr != nil {
 		return &http.Response{}, reqErr
 	}
 
-	req.Header.Add("Accept", h.Accept)
-	req.Header.Add("X-Plex-Platform", h.Platform)
-	req.Header.Add("X-Plex-Platform-Version", h.PlatformVersion)
-	req.Header.Add("X-Plex-Provides", h.Provides)
-	req.Header.Add("X-Plex-Client-Identifier", p.ClientIdentifier)
-	req.Header.Add("X-Plex-Product", h.Product)
-	req.Header.Add("X-Plex-Version", h.Version)
-	req.Header.Add("X-Plex-Device", h.Device)
-	// req.Header.Add("X-Plex-Container-Size", h.ContainerSize)
-	// req.Header.Add("X-Plex-Container-Start", h.ContainerStart)
-	req.Header.Add("X-Plex-Token", p.Token)
+	resp, err := p.do(&client, req)
 
-	// optional headers
-	if h.TargetClientIdentifier != "" {
-		req.Header.Add("X-Plex-Target-Identifier", h.TargetClientIdentifier)
+	if err != nil {
+		return &http.Response{}, err
 	}
 
-	resp, err := client.Do(req)
-
-	if err != nil {
+	if err := p.runResponseHook(resp); err != nil {
 		return &http.Response{}, err
 	}
 
 	return resp, nil
 }
 
-func get(query string, h headers) (*http.Response, error) {
-	client := http.Client{
-		Timeout: 3 * time.Second,
+// getOnce is get without retries, for requests that must not be repeated (i.e. remote control commands)
+func (p *Plex) getOnce(query string, h headers) (*http.Response, error) {
+	client := p.HTTPClient
+
+	req, reqErr := p.newGetRequest(query, h)
+
+	if reqErr != nil {
+		return &http.Response{}, reqErr
 	}
 
-	req, err := http.NewRequest("GET", query, nil)
+	resp, err := p.send(&client, req)
 
 	if err != nil {
 		return &http.Response{}, err
 	}
 
+	if err := p.runResponseHook(resp); err != nil {
+		return &http.Response{}, err
+	}
+
+	return resp, nil
+}
+
+// newGetRequest builds a GET request with the X-Plex-* headers of h
+func (p *Plex) newGetRequest(query string, h headers) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(p.requestContext(), "GET", query, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
 	req.Header.Add("Accept", h.Accept)
 	req.Header.Add("X-Plex-Platform", h.Platform)
 	req.Header.Add("X-Plex-Platform-Version", h.PlatformVersion)
 	req.Header.Add("X-Plex-Provides", h.Provides)
-	req.Header.Add("X-Plex-Client-Identifier", h.ClientIdentifier)
+	req.Header.Add("X-Plex-Client-Identifier", p.ClientIdentifier)
 	req.Header.Add("X-Plex-Product", h.Product)
 	req.Header.Add("X-Plex-Version", h.Version)
 	req.Header.Add("X-Plex-Device", h.Device)
-	// req.Header.Add("X-Plex-Container-Size", h.ContainerSize)
-	// req.Header.Add("X-Plex-Container-Start", h.ContainerStart)
-	if h.Token != "" {
-		req.Header.Add("X-Plex-Token", h.Token)
+	if p.Token != "" {
+		req.Header.Add("X-Plex-Token", p.Token)
 	}
 
-	resp, err := client.Do(req)
+	// optional headers
+	if h.TargetClientIdentifier != "" {
+		req.Header.Add("X-Plex-Target-Identifier", h.TargetClientIdentifier)
+		req.Header.Add("X-Plex-Target-Client-Identifier", h.TargetClientIdentifier)
+	}
 
-	if err != nil {
-		return &http.Response{}, err
+	if h.Language != "" {
+		req.Header.Add("X-Plex-Language", h.Language)
+		req.Header.Add("Accept-Language", h.Language)
 	}
 
-	return resp, nil
+	if h.ContainerStart != "" {
+		req.Header.Add("X-Plex-Container-Start", h.ContainerStart)
+	}
+
+	if h.ContainerSize != "" {
+		req.Header.Add("X-Plex-Container-Size", h.ContainerSize)
+	}
+
+	return req, nil
 }
 
 func (p *Plex) delete(query string, h headers) (*http.Response, error) {
 	client := p.HTTPClient
 
-	req, reqErr := http.NewRequest("DELETE", query, nil)
+	req, reqErr := http.NewRequestWithContext(p.requestContext(), "DELETE", query, nil)
 
 	if reqErr != nil {
 		return &http.Response{}, reqErr
@@ -147,26 +179,38 @@ func (p *Plex) delete(query string, h headers) (*http.Response, error) {
 	req.Header.Add("X-Plex-Device", h.Device)
 	// req.Header.Add("X-Plex-Container-Size", h.ContainerSize)
 	// req.Header.Add("X-Plex-Container-Start", h.ContainerStart)
-	req.Header.Add("X-Plex-Token", p.Token)
+	if p.Token != "" {
+		req.Header.Add("X-Plex-Token", p.Token)
+	}
 
 	// optional headers
 	if h.TargetClientIdentifier != "" {
 		req.Header.Add("X-Plex-Target-Identifier", h.TargetClientIdentifier)
+		req.Header.Add("X-Plex-Target-Client-Identifier", h.TargetClientIdentifier)
+	}
+
+	if h.Language != "" {
+		req.Header.Add("X-Plex-Language", h.Language)
+		req.Header.Add("Accept-Language", h.Language)
 	}
 
-	resp, err := client.Do(req)
+	resp, err := p.do(&client, req)
 
 	if err != nil {
 		return &http.Response{}, err
 	}
 
+	if err := p.runResponseHook(resp); err != nil {
+		return &http.Response{}, err
+	}
+
 	return resp, nil
 }
 
 func (p *Plex) post(query string, body []byte, h headers) (*http.Response, error) {
 	client := p.HTTPClient
 
-	req, err := http.NewRequest("POST", query, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(p.requestContext(), "POST", query, bytes.NewBuffer(body))
 
 	if err != nil {
 		return &http.Response{}, err
@@ -183,53 +227,31 @@ func (p *Plex) post(query string, body []byte, h headers) (*http.Response, error
 	req.Header.Add("X-Plex-Device", h.Device)
 	// req.Header.Add("X-Plex-Container-Size", h.ContainerSize)
 	// req.Header.Add("X-Plex-Container-Start", h.ContainerStart)
-	req.Header.Add("X-Plex-Token", p.Token)
-	req.Header.Add("Content-Type", h.ContentType)
+	if p.Token != "" {
+		req.Header.Add("X-Plex-Token", p.Token)
+	}
+	if h.ContentType != "" {
+		req.Header.Add("Content-Type", h.ContentType)
+	}
 
 	// optional headers
 	if h.TargetClientIdentifier != "" {
 		req.Header.Add("X-Plex-Target-Identifier", h.TargetClientIdentifier)
+		req.Header.Add("X-Plex-Target-Client-Identifier", h.TargetClientIdentifier)
 	}
 
-	resp, err := client.Do(req)
-
-	if err != nil {
-		return &http.Response{}, err
+	if h.Language != "" {
+		req.Header.Add("X-Plex-Language", h.Language)
+		req.Header.Add("Accept-Language", h.Language)
 	}
 
-	return resp, nil
-}
-
-// post sends a POST request and is the same as plex.post while omitting the plex token header
-func post(query string, body []byte, h headers) (*http.Response, error) {
-	client := http.Client{
-		Timeout: 3 * time.Second,
-	}
-
-	req, err := http.NewRequest("POST", query, bytes.NewBuffer(body))
+	resp, err := p.do(&client, req)
 
 	if err != nil {
 		return &http.Response{}, err
 	}
 
-	req.Header.Add("Accept", h.Accept)
-	req.Header.Add("X-Plex-Platform", h.Platform)
-	req.Header.Add("X-Plex-Platform-Version", h.PlatformVersion)
-	req.Header.Add("X-Plex-Provides", h.Provides)
-	req.Header.Add("X-Plex-Client-Identifier", h.ClientIdentifier)
-	req.Header.Add("X-Plex-Product", h.Product)
-	req.Header.Add("X-Plex-Version", h.Version)
-	req.Header.Add("X-Plex-Device", h.Device)
-	// req.Header.Add("X-Plex-Container-Size", h.ContainerSize)
-	// req.Header.Add("X-Plex-Container-Start", h.ContainerStart)
-	if h.Token != "" {
-		req.Header.Add("X-Plex-Token", h.Token)
-	}
-	req.Header.Add("Content-Type", h.ContentType)
-
-	resp, err := client.Do(req)
-
-	if err != nil {
+	if err := p.runResponseHook(resp); err != nil {
 		return &http.Response{}, err
 	}
 
@@ -239,7 +261,7 @@ func post(query string, body []byte, h headers) (*http.Response, error) {
 func (p *Plex) put(query string, body []byte, h headers) (*http.Response, error) {
 	client := p.HTTPClient
 
-	req, reqErr := http.NewRequest("PUT", query, bytes.NewBuffer(body))
+	req, reqErr := http.NewRequestWithContext(p.requestContext(), "PUT", query, bytes.NewBuffer(body))
 
 	if reqErr != nil {
 		return &http.Response{}, reqErr
@@ -256,18 +278,104 @@ func (p *Plex) put(query string, body []byte, h headers) (*http.Response, error)
 	req.Header.Add("X-Plex-Device", h.Device)
 	// req.Header.Add("X-Plex-Container-Size", h.ContainerSize)
 	// req.Header.Add("X-Plex-Container-Start", h.ContainerStart)
-	req.Header.Add("X-Plex-Token", p.Token)
+	if p.Token != "" {
+		req.Header.Add("X-Plex-Token", p.Token)
+	}
 
 	// optional headers
 	if h.TargetClientIdentifier != "" {
 		req.Header.Add("X-Plex-Target-Identifier", h.TargetClientIdentifier)
+		req.Header.Add("X-Plex-Target-Client-Identifier", h.TargetClientIdentifier)
+	}
+
+	if h.Language != "" {
+		req.Header.Add("X-Plex-Language", h.Language)
+		req.Header.Add("Accept-Language", h.Language)
 	}
 
-	resp, err := client.Do(req)
+	resp, err := p.do(&client, req)
 
 	if err != nil {
 		return &http.Response{}, err
 	}
 
+	if err := p.runResponseHook(resp); err != nil {
+		return &http.Response{}, err
+	}
+
 	return resp, nil
 }
+
+// requestContext returns the context of p's requests, see WithContext
+func (p *Plex) requestContext() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+
+	return p.ctx
+}
+
+// do sends a request with client, retrying idempotent requests according to p.Retry.
+// Retries stop as soon as the context of the request is done.
+func (p *Plex) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return p.send(client, req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := p.send(client, req)
+
+		retry := err != nil || resp.StatusCode >= http.StatusInternalServerError
+
+		if !retry || attempt >= p.Retry.Count || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(p.Retry.delay(attempt))
+
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send waits for the rate limiter, if any, then sends a request with client
+func (p *Plex) send(client *http.Client, req *http.Request) (*http.Response, error) {
+	if p.limiter != nil {
+		if err := p.limiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	return client.Do(req)
+}
+
+// runResponseHook passes resp to p.ResponseHook, if any. The body is buffered so that both the hook
+// and the caller can read it.
+func (p *Plex) runResponseHook(resp *http.Response) error {
+	if p.ResponseHook == nil {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	p.ResponseHook(resp)
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return nil
+}
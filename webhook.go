@@ -58,34 +58,48 @@ type Webhook struct {
 // WebhookEvents holds the actions for each webhook events
 type WebhookEvents struct {
 	events map[string]func(w Webhook)
+	// Logger receives errors of incoming webhooks. Nothing is logged when it is nil.
+	Logger Logger
 }
 
-// Handler listens for plex webhooks and executes the corresponding function
-func (wh *WebhookEvents) Handler(w http.ResponseWriter, r *http.Request) {
+// ParseWebhook reads the webhook plex sent in the multipart "payload" field of r
+func ParseWebhook(r *http.Request) (*Webhook, error) {
 	if err := r.ParseMultipartForm(0); err != nil {
-		fmt.Printf("can not read form: %v", err)
-		return
+		return nil, fmt.Errorf("can not read form: %v", err)
+	}
+
+	payload, hasPayload := r.MultipartForm.Value["payload"]
+
+	if !hasPayload || len(payload) == 0 {
+		return nil, errors.New("webhook is missing its payload")
 	}
 
 	var hookEvent Webhook
 
-	payload, hasPayload := r.MultipartForm.Value["payload"]
+	if err := json.Unmarshal([]byte(payload[0]), &hookEvent); err != nil {
+		return nil, fmt.Errorf("can not parse json: %v", err)
+	}
 
-	if hasPayload {
-		if err := json.Unmarshal([]byte(payload[0]), &hookEvent); err != nil {
-			fmt.Printf("can not parse json: %v", err)
-			return
-		}
+	return &hookEvent, nil
+}
+
+// Handler listens for plex webhooks and executes the corresponding function
+func (wh *WebhookEvents) Handler(w http.ResponseWriter, r *http.Request) {
+	hookEvent, err := ParseWebhook(r)
 
-		fn, ok := wh.events[hookEvent.Event]
+	if err != nil {
+		orNoop(wh.Logger).Printf("%v", err)
+		return
+	}
 
-		if !ok {
-			fmt.Printf("unknown event name: %v\n", hookEvent.Event)
-			return
-		}
+	fn, ok := wh.events[hookEvent.Event]
 
-		fn(hookEvent)
+	if !ok {
+		orNoop(wh.Logger).Printf("unknown event name: %v", hookEvent.Event)
+		return
 	}
+
+	fn(*hookEvent)
 }
 
 // newWebhookEvent attaches a function to each webhook event
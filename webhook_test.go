@@ -0,0 +1,35 @@
+package plex
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseWebhook(t *testing.T) {
+	var body bytes.Buffer
+
+	form := multipart.NewWriter(&body)
+
+	if err := form.WriteField("payload", `{"event":"media.play","owner":true,"Account":{"id":1,"title":"jrudio"},"Player":{"local":true,"title":"Living Room"},"Metadata":{"ratingKey":"10","type":"episode","title":"Pilot"}}`); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	form.Close()
+
+	r := httptest.NewRequest("POST", "/webhook", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+
+	hook, err := ParseWebhook(r)
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if hook.Event != "media.play" || hook.Account.Title != "jrudio" || hook.Player.Title != "Living Room" || hook.Metadata.RatingKey != "10" {
+		t.Errorf("Expected: a media.play webhook\n Got: %+v", hook)
+	}
+}
@@ -1,10 +1,17 @@
 package plex
 
 import (
-	"fmt"
-	"log"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -22,19 +29,23 @@ type TimelineEntry struct {
 	UpdatedAt     int64  `json:"updatedAt"`
 }
 
+// Activity is a task the server is running, i.e. a library refresh. Cancellable activities can be
+// canceled with CancelActivity
+type Activity struct {
+	Cancellable bool   `json:"cancellable"`
+	Progress    int64  `json:"progress"`
+	Subtitle    string `json:"subtitle"`
+	Title       string `json:"title"`
+	Type        string `json:"type"`
+	UserID      int64  `json:"userID"`
+	UUID        string `json:"uuid"`
+}
+
 // ActivityNotification ...
 type ActivityNotification struct {
-	Activity struct {
-		Cancellable bool   `json:"cancellable"`
-		Progress    int64  `json:"progress"`
-		Subtitle    string `json:"subtitle"`
-		Title       string `json:"title"`
-		Type        string `json:"type"`
-		UserID      int64  `json:"userID"`
-		UUID        string `json:"uuid"`
-	} `json:"Activity"`
-	Event string `json:"event"`
-	UUID  string `json:"uuid"`
+	Activity Activity `json:"Activity"`
+	Event    string   `json:"event"`
+	UUID     string   `json:"uuid"`
 }
 
 // StatusNotification ...
@@ -70,6 +81,15 @@ type BackgroundProcessingQueueEventNotification struct {
 	QueueID int64  `json:"queueID"`
 }
 
+// AccountNotification is sent when the server's accounts change, i.e. a home user or a share is added or removed
+type AccountNotification struct {
+	AccountID  int64  `json:"accountID"`
+	Event      string `json:"event"`
+	Identifier string `json:"identifier"`
+	Title      string `json:"title"`
+	Type       string `json:"type"`
+}
+
 // TranscodeSession ...
 type TranscodeSession struct {
 	AudioChannels        int64   `json:"audioChannels"`
@@ -92,17 +112,83 @@ type TranscodeSession struct {
 	VideoDecision        string  `json:"videoDecision"`
 }
 
-// Setting ...
+// Setting is a server preference. Default and Value are always strings
+// regardless of the setting's Type (bool, int, double or text)
 type Setting struct {
-	Advanced bool   `json:"advanced"`
-	Default  bool   `json:"default"`
-	Group    string `json:"group"`
-	Hidden   bool   `json:"hidden"`
-	ID       string `json:"id"`
-	Label    string `json:"label"`
-	Summary  string `json:"summary"`
-	Type     string `json:"type"`
-	Value    string `json:"value"`
+	Advanced   bool   `json:"advanced"`
+	Default    string `json:"default"`
+	EnumValues string `json:"enumValues"`
+	Group      string `json:"group"`
+	Hidden     bool   `json:"hidden"`
+	ID         string `json:"id"`
+	Label      string `json:"label"`
+	Summary    string `json:"summary"`
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+}
+
+// SettingOption is an allowed value of an enumerated setting
+type SettingOption struct {
+	Value string
+	Label string
+}
+
+// UnmarshalJSON converts the default and value of a setting to strings as plex sends them as their native type
+func (s *Setting) UnmarshalJSON(data []byte) error {
+	type setting Setting
+
+	var raw struct {
+		setting
+		Default json.RawMessage `json:"default"`
+		Value   json.RawMessage `json:"value"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*s = Setting(raw.setting)
+	s.Default = rawToString(raw.Default)
+	s.Value = rawToString(raw.Value)
+
+	return nil
+}
+
+// Options returns the allowed values of an enumerated setting, or nil if the setting accepts any value
+func (s Setting) Options() []SettingOption {
+	if s.EnumValues == "" {
+		return nil
+	}
+
+	var options []SettingOption
+
+	for _, enum := range strings.Split(s.EnumValues, "|") {
+		split := strings.SplitN(enum, ":", 2)
+
+		option := SettingOption{Value: split[0], Label: split[0]}
+
+		if len(split) == 2 {
+			option.Label = split[1]
+		}
+
+		options = append(options, option)
+	}
+
+	return options
+}
+
+func rawToString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+
+	var str string
+
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str
+	}
+
+	return string(raw)
 }
 
 // NotificationContainer read pms notifications
@@ -119,6 +205,8 @@ type NotificationContainer struct {
 
 	BackgroundProcessingQueueEventNotification []BackgroundProcessingQueueEventNotification `json:"BackgroundProcessingQueueEventNotification"`
 
+	AccountNotification []AccountNotification `json:"AccountUpdateNotification"`
+
 	TranscodeSession []TranscodeSession `json:"TranscodeSession"`
 
 	Setting []Setting `json:"Setting"`
@@ -134,6 +222,7 @@ type NotificationContainer struct {
 	// backgroundProcessingQueue,
 	// transcodeSession.update
 	// transcodeSession.end
+	// account
 	Type string `json:"type"`
 }
 
@@ -179,22 +268,54 @@ func (e *NotificationEvents) OnTranscodeUpdate(fn func(n NotificationContainer))
 	e.events["transcodeSession.update"] = fn
 }
 
-// SubscribeToNotifications connects to your server via websockets listening for events
-func (p *Plex) SubscribeToNotifications(events *NotificationEvents, interrupt <-chan interface{}, errCb func(error), doneCb func()) {
-	plexURL, err := url.Parse(p.URL)
+// OnTranscodeStart executes when the server starts transcoding a stream
+func (e *NotificationEvents) OnTranscodeStart(fn func(n NotificationContainer)) {
+	e.events["transcodeSession.start"] = fn
+}
 
-	if err != nil {
-		errCb(err)
-		return
-	}
+// OnTranscodeEnd executes when the server stops transcoding a stream
+func (e *NotificationEvents) OnTranscodeEnd(fn func(n NotificationContainer)) {
+	e.events["transcodeSession.end"] = fn
+}
 
-	websocketURL := url.URL{Scheme: "wss", Host: plexURL.Host, Path: "/:/websockets/notifications"}
+// OnActivity shows the progress of server activities, i.e. library scans or media analysis
+func (e *NotificationEvents) OnActivity(fn func(n NotificationContainer)) {
+	e.events["activity"] = fn
+}
 
-	headers := http.Header{
-		"X-Plex-Token": []string{p.Token},
-	}
+// OnStatus shows server status messages, i.e. when a library scan finishes
+func (e *NotificationEvents) OnStatus(fn func(n NotificationContainer)) {
+	e.events["status"] = fn
+}
 
-	c, _, err := websocket.DefaultDialer.Dial(websocketURL.String(), headers)
+// OnTimeline shows changes to library items, i.e. media being added, updated or deleted
+func (e *NotificationEvents) OnTimeline(fn func(n NotificationContainer)) {
+	e.events["timeline"] = fn
+}
+
+// OnReachability shows whether the server is reachable from outside your network
+func (e *NotificationEvents) OnReachability(fn func(n NotificationContainer)) {
+	e.events["reachability"] = fn
+}
+
+// OnPreference shows server settings that changed
+func (e *NotificationEvents) OnPreference(fn func(n NotificationContainer)) {
+	e.events["preference"] = fn
+}
+
+// OnBackgroundProcessingQueue shows events of the background processing queue, i.e. optimized versions
+func (e *NotificationEvents) OnBackgroundProcessingQueue(fn func(n NotificationContainer)) {
+	e.events["backgroundProcessingQueue"] = fn
+}
+
+// OnAccount shows changes to the accounts of the server (home users, shares)
+func (e *NotificationEvents) OnAccount(fn func(n NotificationContainer)) {
+	e.events["account"] = fn
+}
+
+// SubscribeToNotifications connects to your server via websockets listening for events
+func (p *Plex) SubscribeToNotifications(events *NotificationEvents, interrupt <-chan interface{}, errCb func(error), doneCb func()) {
+	c, err := p.dialNotifications()
 
 	if err != nil {
 		errCb(err)
@@ -207,61 +328,228 @@ func (p *Plex) SubscribeToNotifications(events *NotificationEvents, interrupt <-
 		defer c.Close()
 		defer close(done)
 
+		if err := p.readNotifications(c, events); err != nil {
+			errCb(err)
+			return
+		}
+
+		doneCb()
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
 		for {
-			var notif WebsocketNotification
-			err := c.ReadJSON(&notif)
+			select {
+			case t := <-ticker.C:
+				err := c.WriteMessage(websocket.TextMessage, []byte(t.String()))
 
-			// If the connection was normally closed, everything is fine, return as expected
-			if err != nil && websocket.IsCloseError(err, websocket.CloseNormalClosure) {
-				doneCb()
-				return
-			}
+				if err != nil {
+					errCb(err)
+				}
+			case <-interrupt:
+				// To cleanly close a connection, a client should send a close
+				// frame and wait for the server to close the connection.
+				_ = c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 
-			// But if there was a real unknown error, exit and report the error
-			if err != nil {
-				fmt.Println("read:", err)
-				errCb(err)
+				select {
+				case <-done:
+				case <-time.After(time.Second):
+					orNoop(p.Logger).Printf("WebSocket closing")
+					c.Close()
+				}
 				return
 			}
+		}
+	}()
+}
+
+// dialNotifications opens the notification websocket of the server
+func (p *Plex) dialNotifications() (*websocket.Conn, error) {
+	plexURL, err := url.Parse(p.URL)
 
-			// fmt.Printf("\t%s\n", string(message))
+	if err != nil {
+		return nil, err
+	}
 
-			eventCallback, ok := events.events[notif.Type]
+	websocketURL := url.URL{Scheme: "wss", Host: plexURL.Host, Path: "/:/websockets/notifications"}
 
-			if !ok {
-				log.Printf("Unknown websocket event name: %v\n", notif.Type)
-				continue
-			}
+	headers := http.Header{}
+	headers.Add("X-Plex-Platform", p.Headers.Platform)
+	headers.Add("X-Plex-Platform-Version", p.Headers.PlatformVersion)
+	headers.Add("X-Plex-Provides", p.Headers.Provides)
+	headers.Add("X-Plex-Client-Identifier", p.ClientIdentifier)
+	headers.Add("X-Plex-Product", p.Headers.Product)
+	headers.Add("X-Plex-Version", p.Headers.Version)
+	headers.Add("X-Plex-Device", p.Headers.Device)
+	headers.Add("X-Plex-Token", p.Token)
+
+	dialer := *websocket.DefaultDialer
+	dialer.EnableCompression = true
+
+	// use the same tls config as api requests, i.e. WithInsecureSkipVerify
+	if transport, ok := p.HTTPClient.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+		dialer.TLSClientConfig = transport.TLSClientConfig.Clone()
+	}
+
+	c, _, err := dialer.Dial(websocketURL.String(), headers)
+
+	return c, err
+}
+
+// Notifications connects to your server via websockets and delivers every notification on the
+// returned channel until ctx is canceled or the connection closes, after which both channels are
+// closed. The error channel receives at most one error, i.e. a failed connection. Use the Type of a
+// notification to tell notifications apart.
+func (p *Plex) Notifications(ctx context.Context) (<-chan NotificationContainer, <-chan error) {
+	notifications := make(chan NotificationContainer)
+	errs := make(chan error, 1)
 
-			eventCallback(notif.NotificationContainer)
+	c, err := p.dialNotifications()
+
+	if err != nil {
+		errs <- err
+		close(notifications)
+		close(errs)
+
+		return notifications, errs
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(errs)
+		defer close(notifications)
+		defer close(done)
+
+		// closing the connection after ctx is canceled is not an error
+		if err := p.streamNotifications(ctx, c, notifications); err != nil && ctx.Err() == nil {
+			errs <- err
 		}
 	}()
 
 	go func() {
+		defer c.Close()
+
 		ticker := time.NewTicker(time.Second)
 		defer ticker.Stop()
 
 		for {
 			select {
 			case t := <-ticker.C:
-				err := c.WriteMessage(websocket.TextMessage, []byte(t.String()))
-
-				if err != nil {
-					errCb(err)
+				if err := c.WriteMessage(websocket.TextMessage, []byte(t.String())); err != nil {
+					orNoop(p.Logger).Printf("ping: %v", err)
+					return
 				}
-			case <-interrupt:
-				// To cleanly close a connection, a client should send a close
-				// frame and wait for the server to close the connection.
+			case <-ctx.Done():
 				_ = c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 
 				select {
 				case <-done:
 				case <-time.After(time.Second):
-					fmt.Println("WebSocket closing")
-					c.Close()
+					orNoop(p.Logger).Printf("WebSocket closing")
 				}
 				return
+			case <-done:
+				return
 			}
 		}
 	}()
+
+	return notifications, errs
+}
+
+// readNotifications dispatches notifications to events until the connection closes. It returns nil
+// when the connection was closed normally.
+func (p *Plex) readNotifications(c *websocket.Conn, events *NotificationEvents) error {
+	notifications := make(chan NotificationContainer)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(notifications)
+
+		errs <- p.streamNotifications(context.Background(), c, notifications)
+	}()
+
+	for notif := range notifications {
+		eventCallback, ok := events.events[notif.Type]
+
+		if !ok {
+			orNoop(p.Logger).Printf("Unknown websocket event name: %v", notif.Type)
+			continue
+		}
+
+		eventCallback(notif)
+	}
+
+	return <-errs
+}
+
+// streamNotifications sends the notifications of c to notifications until the connection closes or
+// ctx is canceled. It returns nil when the connection was closed normally.
+func (p *Plex) streamNotifications(ctx context.Context, c *websocket.Conn, notifications chan<- NotificationContainer) error {
+	for {
+		messageType, message, err := c.ReadMessage()
+
+		// If the connection was normally closed, everything is fine, return as expected
+		if err != nil && websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			return nil
+		}
+
+		// But if there was a real unknown error, exit and report the error
+		if err != nil {
+			orNoop(p.Logger).Printf("read: %v", err)
+			return err
+		}
+
+		notif, err := decodeNotification(messageType, message)
+
+		if err != nil {
+			orNoop(p.Logger).Printf("decode: %v", err)
+			continue
+		}
+
+		select {
+		case notifications <- notif.NotificationContainer:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// decodeNotification unmarshals a websocket message, decompressing binary messages
+// some servers send compressed with gzip, zlib or deflate
+func decodeNotification(messageType int, message []byte) (WebsocketNotification, error) {
+	var notif WebsocketNotification
+
+	if messageType == websocket.BinaryMessage {
+		var r io.ReadCloser
+		var err error
+
+		switch {
+		case len(message) > 1 && message[0] == 0x1f && message[1] == 0x8b:
+			r, err = gzip.NewReader(bytes.NewReader(message))
+		case len(message) > 0 && message[0] == 0x78:
+			r, err = zlib.NewReader(bytes.NewReader(message))
+		case len(message) > 0 && message[0] == '{':
+			r = ioutil.NopCloser(bytes.NewReader(message))
+		default:
+			r = flate.NewReader(bytes.NewReader(message))
+		}
+
+		if err != nil {
+			return notif, err
+		}
+
+		defer r.Close()
+
+		if message, err = ioutil.ReadAll(r); err != nil {
+			return notif, err
+		}
+	}
+
+	err := json.Unmarshal(message, &notif)
+
+	return notif, err
 }
@@ -0,0 +1,147 @@
+package plex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDecodeNotification(t *testing.T) {
+	message := []byte(`{"NotificationContainer":{"type":"playing","size":1,"PlaySessionStateNotification":[{"sessionKey":"1","state":"paused"}]}}`)
+
+	compress := func(newWriter func(io.Writer) io.WriteCloser) []byte {
+		var buf bytes.Buffer
+
+		w := newWriter(&buf)
+		w.Write(message)
+		w.Close()
+
+		return buf.Bytes()
+	}
+
+	tests := map[string]struct {
+		messageType int
+		message     []byte
+	}{
+		"text": {websocket.TextMessage, message},
+		"gzip": {websocket.BinaryMessage, compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })},
+		"zlib": {websocket.BinaryMessage, compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })},
+	}
+
+	for name, test := range tests {
+		notif, err := decodeNotification(test.messageType, test.message)
+
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if notif.Type != "playing" || len(notif.PlaySessionStateNotification) != 1 {
+			t.Errorf("%s: Expected: a playing notification\n Got: %+v", name, notif)
+		}
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+
+		if err != nil {
+			return
+		}
+
+		defer c.Close()
+
+		c.WriteMessage(websocket.TextMessage, []byte(`{"NotificationContainer":{"type":"playing","size":1,"PlaySessionStateNotification":[{"sessionKey":"1","state":"playing"}]}}`))
+		c.WriteMessage(websocket.TextMessage, []byte(`{"NotificationContainer":{"type":"activity","size":1}}`))
+
+		// answer the close handshake
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123", WithInsecureSkipVerify())
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notifications, errs := p.Notifications(ctx)
+
+	var types []string
+
+	for notif := range notifications {
+		types = append(types, notif.Type)
+
+		if len(types) == 2 {
+			cancel()
+		}
+	}
+
+	if err := <-errs; err != nil {
+		t.Error(err.Error())
+	}
+
+	if len(types) != 2 || types[0] != "playing" || types[1] != "activity" {
+		t.Errorf("Expected: playing and activity notifications\n Got: %v", types)
+	}
+}
+
+func TestAccountNotification(t *testing.T) {
+	server := newNotificationServer(func(c *websocket.Conn) {
+		c.WriteMessage(websocket.TextMessage, []byte(`{"NotificationContainer":{"type":"account","size":1,"AccountUpdateNotification":[{"accountID":42,"event":"user.updated","identifier":"com.plexapp.system.accounts","title":"Jane","type":"account"}]}}`))
+
+		answerClose(c)
+	})
+	defer server.Close()
+
+	p, err := New(server.URL, "abc123", WithInsecureSkipVerify())
+
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	accounts := make(chan NotificationContainer, 1)
+
+	events := NewNotificationEvents()
+	events.OnAccount(func(n NotificationContainer) {
+		accounts <- n
+	})
+
+	client := p.NewNotificationClient(events)
+
+	if err := client.Start(); err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	defer client.Close()
+
+	select {
+	case n := <-accounts:
+		if len(n.AccountNotification) != 1 || n.AccountNotification[0].AccountID != 42 || n.AccountNotification[0].Title != "Jane" {
+			t.Errorf("Expected: the update of account 42\n Got: %+v", n.AccountNotification)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected: an account notification\n Got: none")
+	}
+}